@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	config "github.com/yur4uwe/cmd-project-manager/config"
+	import_export "github.com/yur4uwe/cmd-project-manager/import_export"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// Exit codes of the subcommands
+const (
+	EXIT_OK = iota
+	EXIT_FAILURE
+	EXIT_USAGE
+)
+
+// Env is what every subcommand runs against.
+type Env struct {
+	Config config.Config
+	Store  project.ProjectStore
+	// Query answers listings from the indexes of the backend, nil if it
+	// has none and they are answered in memory.
+	Query project.ProjectQuerier
+}
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(env *Env, args []string) int
+}
+
+var commands []command
+
+// hidden_commands are run like commands but left out of the usage and of
+// completion.
+var hidden_commands []command
+
+func init() {
+	commands = []command{
+		{"add", "add [--description d] [--tags t] [--field name=value]... [--dir parent] name", "create a project directory with a git repository and register it", runAdd},
+		{"link", "link [--name n] [--description d] [--tags t] [--field name=value]... [dir]", "register an existing directory as a project", runLink},
+		{"list", "list [--tag t]... [--where filter]... [--since date] [--sort key] [--archived | --all] [--json | --format tmpl] [-0]", "list the projects that aren't archived, or those with all the given tags and matching fields", runList},
+		{"show", "show [--json | --format tmpl] <project>", "show everything about a project", runShow},
+		{"update", "update [--name n] [--description d] [--path p] [--tags t] [--field name=value]... <project>", "change a project", runUpdate},
+		{"tags", "tags", "count the projects of every tag", runTags},
+		{"note", "note [--edit | --set text | --append text | --clear] [--add item]... [--toggle n] [--remove n] <project>", "show or change the notes and checklist of a project", runNote},
+		{"pin", "pin [project]", "pin a project to the top of the menus, or list the pinned ones", runPin},
+		{"unpin", "unpin <project>", "unpin a project", runUnpin},
+		{"archive", "archive [--compress] <project>", "hide a project from the lists, optionally packing its directory into a tar.gz file", runArchive},
+		{"restore", "restore <project>", "bring an archived project back, unpacking its directory", runRestore},
+		{"group", "group [create | add | remove | rename | delete | show | open [--workspace] | workspace] <group> [project]...", "list the project groups, change one, or open all its projects in the editor or as one VS Code workspace", runGroup},
+		{"rm", "rm [--yes] <project>", "remove a project, its directory is kept", runRemove},
+		{"open", "open [--explorer] <project>", "open a project in VS Code or the file explorer", runOpen},
+		{"path", "path [--copy] <project>", "print the directory of a project", runPath},
+		{"exec", "exec <project> -- command", "run a command in the directory of a project", runExec},
+		{"foreach", "foreach [--parallel N] [--tag t]... [--where filter]... -- command", "run a command in the directory of every project, or those with all the given tags", runForeach},
+		{"pick", "pick [--format tmpl] [-0]", "pick a project with a fuzzy finder and print its path", runPick},
+		{"cd", "cd [project]", "print the directory of a project for the shell function of init to change into", runCd},
+		{"init", "init <shell>", "print the shell function that makes `pm cd` change directory, for bash, zsh or fish", runInit},
+		{"export", "export [--format f] [-o file]", "write the registry as json, csv, yaml or toml", runExport},
+		{"import", "import [--format f] [--map a=Field] file", "add or update projects from a json, csv, yaml or toml file", runImport},
+		{"import-from", "import-from source [file]", "pick projects to import from " + strings.Join(import_export.Sources, ", "), runImportFrom},
+		{"undo", "undo", "revert the last change to the registry", runUndo},
+		{"redo", "redo", "reapply the last undone change", runRedo},
+		{"completion", "completion <shell>", "print the completion script for bash, zsh or fish", runCompletion},
+	}
+
+	hidden_commands = []command{
+		{"__complete", "__complete words...", "print the completions for the words after pm", runComplete},
+	}
+}
+
+// IsCommand reports whether name is a known subcommand.
+func IsCommand(name string) bool {
+	_, ok := findCommand(name)
+	return ok
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range append(commands, hidden_commands...) {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+
+	return command{}, false
+}
+
+// Run executes the subcommand named by args[0] and returns the exit code.
+func Run(env *Env, args []string) int {
+	if len(args) == 0 {
+		Usage(os.Stderr)
+		return EXIT_USAGE
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+		Usage(os.Stderr)
+		return EXIT_USAGE
+	}
+
+	return cmd.run(env, args[1:])
+}
+
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: pm [flags] [command [arguments]]")
+	fmt.Fprintln(w, "\nWithout a command the interactive menu is started.")
+	fmt.Fprintln(w, "A <project> is its ID, its name or the start of its ID.")
+	fmt.Fprintln(w, "\nCommands:")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  pm %s\n        %s\n", cmd.usage, cmd.summary)
+	}
+}
+
+// parseFlags parses flags that may come before, between or after the
+// positional arguments. Everything after "--" is positional.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for len(args) > 0 {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		rest := flags.Args()
+		consumed := len(args) - len(rest)
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+
+		if len(rest) == 0 {
+			break
+		}
+
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	return positional, nil
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors
+// instead of exiting.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("pm "+name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+
+	return flags
+}
+
+// fail prints err to stderr and returns EXIT_FAILURE.
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	return EXIT_FAILURE
+}
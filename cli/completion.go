@@ -0,0 +1,353 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	import_export "github.com/yur4uwe/cmd-project-manager/import_export"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// What a flag value or positional argument is, so it can be completed.
+const (
+	COMPLETE_NOTHING = ""
+	COMPLETE_SWITCH  = "switch"
+	COMPLETE_TEXT    = "text"
+	COMPLETE_FILE    = "file"
+	COMPLETE_PROJECT = "project"
+	COMPLETE_TAG     = "tag"
+	COMPLETE_FIELD   = "field"
+	COMPLETE_SORT    = "sort"
+	COMPLETE_GROUP   = "group"
+	COMPLETE_FORMAT  = "format"
+	COMPLETE_FIELDS  = "fields"
+	COMPLETE_SOURCE  = "source"
+	COMPLETE_SHELL   = "shell"
+
+	// COMPLETE_GROUP_COMMAND is a subcommand of pm group
+	COMPLETE_GROUP_COMMAND = "group-command"
+)
+
+// FILE_DIRECTIVE is printed by __complete when the shell should complete
+// file names as well.
+const FILE_DIRECTIVE = ":file"
+
+type completion struct {
+	// flags maps each flag to what its value is, COMPLETE_SWITCH for flags
+	// without a value.
+	flags map[string]string
+	// args is what each positional argument is, in order.
+	args []string
+	// rest is what the positional arguments after args are.
+	rest string
+}
+
+var completions = map[string]completion{
+	"add": {flags: map[string]string{"--description": COMPLETE_TEXT, "--tags": COMPLETE_TAG, "--field": COMPLETE_FIELD, "--dir": COMPLETE_FILE}},
+	"link": {
+		flags: map[string]string{"--name": COMPLETE_TEXT, "--description": COMPLETE_TEXT, "--tags": COMPLETE_TAG, "--field": COMPLETE_FIELD},
+		args:  []string{COMPLETE_FILE},
+	},
+	"list": {
+		flags: withFlags(outputCompletion, map[string]string{
+			"--tag": COMPLETE_TAG, "--where": COMPLETE_FIELD, "--since": COMPLETE_TEXT, "--sort": COMPLETE_SORT, "--archived": COMPLETE_SWITCH, "--all": COMPLETE_SWITCH,
+		}),
+	},
+	"tags": {},
+	"pin":  {args: []string{COMPLETE_PROJECT}},
+	"note": {
+		flags: map[string]string{
+			"--edit": COMPLETE_SWITCH, "--set": COMPLETE_TEXT, "--append": COMPLETE_TEXT, "--clear": COMPLETE_SWITCH,
+			"--add": COMPLETE_TEXT, "--toggle": COMPLETE_TEXT, "--remove": COMPLETE_TEXT,
+		},
+		args: []string{COMPLETE_PROJECT},
+	},
+	"unpin":   {args: []string{COMPLETE_PROJECT}},
+	"archive": {flags: map[string]string{"--compress": COMPLETE_SWITCH}, args: []string{COMPLETE_PROJECT}},
+	"restore": {args: []string{COMPLETE_PROJECT}},
+	"group": {
+		flags: map[string]string{"--workspace": COMPLETE_SWITCH},
+		args:  []string{COMPLETE_GROUP_COMMAND, COMPLETE_GROUP},
+		rest:  COMPLETE_PROJECT,
+	},
+	"show": {flags: outputCompletion, args: []string{COMPLETE_PROJECT}},
+	"update": {
+		flags: map[string]string{"--name": COMPLETE_TEXT, "--description": COMPLETE_TEXT, "--path": COMPLETE_FILE, "--tags": COMPLETE_TAG, "--field": COMPLETE_FIELD},
+		args:  []string{COMPLETE_PROJECT},
+	},
+	"rm":      {flags: map[string]string{"--yes": COMPLETE_SWITCH}, args: []string{COMPLETE_PROJECT}},
+	"open":    {flags: map[string]string{"--explorer": COMPLETE_SWITCH}, args: []string{COMPLETE_PROJECT}},
+	"path":    {flags: map[string]string{"--copy": COMPLETE_SWITCH}, args: []string{COMPLETE_PROJECT}},
+	"pick":    {flags: map[string]string{"--format": COMPLETE_TEXT, "-0": COMPLETE_SWITCH}},
+	"cd":      {args: []string{COMPLETE_PROJECT}},
+	"exec":    {args: []string{COMPLETE_PROJECT}},
+	"foreach": {flags: map[string]string{"--parallel": COMPLETE_TEXT, "--tag": COMPLETE_TAG, "--where": COMPLETE_FIELD}},
+	"init":    {args: []string{COMPLETE_SHELL}},
+	"export": {
+		flags: map[string]string{"--format": COMPLETE_FORMAT, "--fields": COMPLETE_FIELDS, "-o": COMPLETE_FILE},
+	},
+	"import": {
+		flags: map[string]string{"--format": COMPLETE_FORMAT, "--map": COMPLETE_TEXT, "--dry-run": COMPLETE_SWITCH, "--yes": COMPLETE_SWITCH},
+		args:  []string{COMPLETE_FILE},
+	},
+	"import-from": {
+		flags: map[string]string{"--dry-run": COMPLETE_SWITCH, "--yes": COMPLETE_SWITCH},
+		args:  []string{COMPLETE_SOURCE, COMPLETE_FILE},
+	},
+	"completion": {args: []string{COMPLETE_SHELL}},
+}
+
+// flagKind returns what the value of flag is. Like the flag package it
+// accepts both -name and --name.
+func (c completion) flagKind(flag string) string {
+	if kind, ok := c.flags[flag]; ok {
+		return kind
+	}
+	if strings.HasPrefix(flag, "--") {
+		return c.flags[flag[1:]]
+	}
+
+	return c.flags["-"+flag]
+}
+
+var outputCompletion = map[string]string{"--json": COMPLETE_SWITCH, "--format": COMPLETE_TEXT, "--null": COMPLETE_SWITCH, "-0": COMPLETE_SWITCH}
+
+// withFlags returns the flags of both maps in a new one.
+func withFlags(a, b map[string]string) map[string]string {
+	flags := make(map[string]string, len(a)+len(b))
+	for flag, kind := range a {
+		flags[flag] = kind
+	}
+	for flag, kind := range b {
+		flags[flag] = kind
+	}
+
+	return flags
+}
+
+var shells = []string{"bash", "fish", "zsh"}
+
+func runCompletion(env *Env, args []string) int {
+	if len(args) != 1 || completionScripts[args[0]] == "" {
+		fmt.Fprintf(os.Stderr, "Usage: pm completion <shell>\nShells: %s\n\n", strings.Join(shells, ", "))
+		fmt.Fprintln(os.Stderr, "Add this to your shell startup file, e.g. for bash:")
+		fmt.Fprintln(os.Stderr, `  eval "$(pm completion bash)"`)
+		fmt.Fprintln(os.Stderr, "or for fish:")
+		fmt.Fprintln(os.Stderr, "  pm completion fish | source")
+		return EXIT_USAGE
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf(completionScripts[args[0]], shellQuote(executable))
+
+	return EXIT_OK
+}
+
+/*
+runComplete is called back by the completion scripts with the words after
+`pm`, the last being the word under the cursor, maybe empty. It prints one
+candidate per line, and FILE_DIRECTIVE if file names fit as well. Matching
+ignores case, like CheckDuplicateNames.
+*/
+func runComplete(env *Env, args []string) int {
+	if len(args) == 0 {
+		return EXIT_OK
+	}
+
+	current := args[len(args)-1]
+
+	var candidates []string
+	if len(args) == 1 {
+		for _, cmd := range commands {
+			candidates = append(candidates, cmd.name)
+		}
+	} else {
+		candidates = completeArguments(env, args[0], args[1:len(args)-1], current)
+	}
+
+	for _, candidate := range candidates {
+		if candidate == FILE_DIRECTIVE || hasPrefixFold(candidate, current) {
+			fmt.Println(candidate)
+		}
+	}
+
+	return EXIT_OK
+}
+
+// completeArguments returns the candidates for the word after words in
+// the arguments of the command named name.
+func completeArguments(env *Env, name string, words []string, current string) []string {
+	spec := completions[name]
+
+	if len(words) > 0 {
+		if kind := spec.flagKind(words[len(words)-1]); kind != COMPLETE_NOTHING && kind != COMPLETE_SWITCH {
+			return candidatesFor(env, kind)
+		}
+	}
+
+	positional := 0
+	only_positional := false
+
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+
+		switch {
+		case only_positional || !strings.HasPrefix(word, "-") || word == "-":
+			positional++
+		case word == "--":
+			only_positional = true
+		case !strings.Contains(word, "=") && spec.flagKind(word) != COMPLETE_SWITCH && spec.flagKind(word) != COMPLETE_NOTHING:
+			// Skip the value of the flag
+			i++
+		}
+	}
+
+	if strings.HasPrefix(current, "-") && !only_positional {
+		var flags []string
+		for flag := range spec.flags {
+			flags = append(flags, flag)
+		}
+		sort.Strings(flags)
+
+		return flags
+	}
+
+	if positional < len(spec.args) {
+		return candidatesFor(env, spec.args[positional])
+	}
+	if spec.rest != COMPLETE_NOTHING {
+		return candidatesFor(env, spec.rest)
+	}
+
+	return nil
+}
+
+func candidatesFor(env *Env, kind string) []string {
+	switch kind {
+	case COMPLETE_PROJECT:
+		if env == nil || env.Store == nil {
+			return nil
+		}
+		projects, err := env.Store.List()
+		if err != nil {
+			return nil
+		}
+		var names []string
+		for _, p := range projects {
+			names = append(names, p.Name)
+		}
+		return names
+	case COMPLETE_TAG:
+		if env == nil || env.Store == nil {
+			return nil
+		}
+		projects, err := env.Store.List()
+		if err != nil {
+			return nil
+		}
+		var tags []string
+		for _, count := range project.CountTags(projects) {
+			tags = append(tags, count.Tag)
+		}
+		return tags
+	case COMPLETE_FIELD:
+		var fields []string
+		for _, def := range project.FieldSchema() {
+			fields = append(fields, def.Name+"=")
+		}
+		return fields
+	case COMPLETE_SORT:
+		return append(project.SortKeys(), project.SORT_OPENED)
+	case COMPLETE_GROUP:
+		groups, err := project.LoadGroups()
+		if err != nil {
+			return nil
+		}
+		var names []string
+		for _, g := range groups {
+			names = append(names, g.Name)
+		}
+		return names
+	case COMPLETE_GROUP_COMMAND:
+		var names []string
+		for _, cmd := range group_commands {
+			names = append(names, cmd.name)
+		}
+		return names
+	case COMPLETE_FILE:
+		return []string{FILE_DIRECTIVE}
+	case COMPLETE_FORMAT:
+		return import_export.Formats
+	case COMPLETE_FIELDS:
+		return import_export.ColumnNames()
+	case COMPLETE_SOURCE:
+		return import_export.Sources
+	case COMPLETE_SHELL:
+		return shells
+	}
+
+	return nil
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// The completion scripts ask `pm __complete` for the candidates. %[1]s is
+// the quoted path of the executable.
+var completionScripts = map[string]string{
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+const bashCompletion = `_pm_complete() {
+    local IFS=$'\n' candidate files=0
+    COMPREPLY=()
+    for candidate in $(command %[1]s __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null); do
+        if [ "$candidate" = ":file" ]; then
+            files=1
+        else
+            COMPREPLY+=("$(printf '%%q' "$candidate")")
+        fi
+    done
+    if [ "$files" = 1 ]; then
+        compopt -o filenames
+        COMPREPLY+=($(compgen -f -- "${COMP_WORDS[COMP_CWORD]}"))
+    fi
+}
+complete -F _pm_complete pm
+`
+
+const zshCompletion = `#compdef pm
+_pm() {
+    local -a candidates matches
+    local candidate files=0
+    candidates=("${(@f)$(command %[1]s __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    for candidate in $candidates; do
+        if [[ $candidate == ":file" ]]; then
+            files=1
+        elif [[ -n $candidate ]]; then
+            matches+=("$candidate")
+        fi
+    done
+    (( ${#matches} )) && compadd -U -- "${matches[@]}"
+    (( files )) && _files
+}
+compdef _pm pm
+`
+
+const fishCompletion = `function __pm_complete
+    set -l words (commandline -opc) (commandline -ct)
+    set -e words[1]
+    command %[1]s __complete $words 2>/dev/null
+end
+complete -c pm -f -a '(__pm_complete | string match -v ":file")'
+complete -c pm -F -n '__pm_complete | string match -q ":file"'
+`
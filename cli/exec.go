@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+func runExec(env *Env, args []string) int {
+	flags := newFlagSet("exec")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) < 2 {
+		fmt.Fprintln(os.Stderr, "Usage: pm exec <project> -- command [arguments]")
+		return EXIT_USAGE
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	cmd := projectCommand(p, positional[1:])
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if startFailed(err) {
+		return fail(err)
+	}
+
+	return exitCode(err)
+}
+
+// foreachResult is how the command went in one project.
+type foreachResult struct {
+	project  project.Project
+	code     int
+	err      error
+	duration time.Duration
+}
+
+func runForeach(env *Env, args []string) int {
+	flags := newFlagSet("foreach")
+	parallel := flags.Int("parallel", 1, "how many projects to run the command in at once")
+	tags := &tagsValue{}
+	flags.Var(tags, "tag", "only run in projects with this tag, may be repeated or comma separated")
+	var where itemsValue
+	flags.Var(&where, "where", "only run in projects whose custom field matches `filter`, may be repeated")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) == 0 || *parallel < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: pm foreach [--parallel N] [--tag t]... [--where filter]... -- command [arguments]")
+		return EXIT_USAGE
+	}
+
+	filters, err := parseFieldFilters(where)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_USAGE
+	}
+
+	projects, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+	projects = project.FilterByFields(project.FilterByTags(project.ActiveProjects(projects), tags.value()), filters)
+
+	width := 0
+	for _, p := range projects {
+		if len(p.Name) > width {
+			width = len(p.Name)
+		}
+	}
+
+	var output_lock sync.Mutex
+	results := make([]foreachResult, len(projects))
+	slots := make(chan struct{}, *parallel)
+	var wg sync.WaitGroup
+
+	for i, p := range projects {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(i int, p project.Project) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			prefix := fmt.Sprintf("%-*s | ", width, p.Name)
+			stdout := &prefixWriter{lock: &output_lock, out: os.Stdout, prefix: prefix}
+			stderr := &prefixWriter{lock: &output_lock, out: os.Stderr, prefix: prefix}
+
+			cmd := projectCommand(p, positional)
+			cmd.Stdout = stdout
+			cmd.Stderr = stderr
+
+			start := time.Now()
+			err := cmd.Run()
+			stdout.Flush()
+			stderr.Flush()
+
+			if startFailed(err) {
+				stderr.Write([]byte(err.Error() + "\n"))
+			}
+
+			results[i] = foreachResult{project: p, code: exitCode(err), err: err, duration: time.Since(start)}
+		}(i, p)
+	}
+
+	wg.Wait()
+
+	return printForeachSummary(results)
+}
+
+// printForeachSummary prints a table of the results to stderr and returns
+// EXIT_FAILURE if the command failed in any project.
+func printForeachSummary(results []foreachResult) int {
+	failed := 0
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nPROJECT\tSTATUS\tTIME")
+
+	for _, result := range results {
+		status := "ok"
+		if result.err != nil {
+			failed++
+			status = fmt.Sprintf("failed (%d)", result.code)
+			if startFailed(result.err) {
+				status = "failed to start"
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.project.Name, status, result.duration.Round(time.Millisecond))
+	}
+	w.Flush()
+
+	fmt.Fprintf(os.Stderr, "%d of %d project(s) failed\n", failed, len(results))
+
+	if failed > 0 {
+		return EXIT_FAILURE
+	}
+
+	return EXIT_OK
+}
+
+// projectCommand prepares command to run in the directory of p, with the
+// project in the environment as PM_PROJECT_ID, PM_PROJECT_NAME and
+// PM_PROJECT_PATH.
+func projectCommand(p project.Project, command []string) *exec.Cmd {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = p.Path
+	cmd.Env = append(os.Environ(),
+		"PM_PROJECT_ID="+p.ID,
+		"PM_PROJECT_NAME="+p.Name,
+		"PM_PROJECT_PATH="+p.Path,
+	)
+
+	return cmd
+}
+
+// exitCode turns the error of running a command into the exit code to pass on.
+func exitCode(err error) int {
+	if err == nil {
+		return EXIT_OK
+	}
+
+	var exit_err *exec.ExitError
+	if errors.As(err, &exit_err) && exit_err.ExitCode() > 0 {
+		return exit_err.ExitCode()
+	}
+
+	return EXIT_FAILURE
+}
+
+// startFailed reports whether err means the command could not be run at
+// all, as opposed to it exiting with an error.
+func startFailed(err error) bool {
+	var exit_err *exec.ExitError
+	return err != nil && !errors.As(err, &exit_err)
+}
+
+// prefixWriter writes every line with prefix in front of it. Lines are
+// written whole under lock, so the output of several writers doesn't mix
+// within a line.
+type prefixWriter struct {
+	lock    *sync.Mutex
+	out     io.Writer
+	prefix  string
+	pending []byte
+}
+
+func (w *prefixWriter) Write(data []byte) (int, error) {
+	w.pending = append(w.pending, data...)
+
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			break
+		}
+
+		w.writeLine(w.pending[:end+1])
+		w.pending = w.pending[end+1:]
+	}
+
+	return len(data), nil
+}
+
+// Flush writes what is left after the last newline.
+func (w *prefixWriter) Flush() {
+	if len(w.pending) > 0 {
+		w.writeLine(append(w.pending, '\n'))
+		w.pending = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	io.WriteString(w.out, w.prefix)
+	w.out.Write(line)
+}
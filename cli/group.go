@@ -0,0 +1,302 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+type groupCommand struct {
+	name  string
+	usage string
+	run   func(env *Env, args []string) int
+}
+
+var group_commands []groupCommand
+
+func init() {
+	group_commands = []groupCommand{
+		{"list", "list", runGroupList},
+		{"show", "show <group>", runGroupShow},
+		{"create", "create <group> [project]...", runGroupCreate},
+		{"add", "add <group> <project>...", runGroupAdd},
+		{"remove", "remove <group> <project>...", runGroupRemove},
+		{"rename", "rename <group> <new name>", runGroupRename},
+		{"delete", "delete <group>", runGroupDelete},
+		{"open", "open [--workspace] <group>", runGroupOpen},
+		{"workspace", "workspace <group>", runGroupWorkspace},
+	}
+}
+
+// runGroup runs the group subcommand named by the first argument, list
+// without one.
+func runGroup(env *Env, args []string) int {
+	if len(args) == 0 {
+		return runGroupList(env, args)
+	}
+
+	for _, cmd := range group_commands {
+		if cmd.name == args[0] {
+			return cmd.run(env, args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown group command %q\n\nUsage:\n", args[0])
+	for _, cmd := range group_commands {
+		fmt.Fprintf(os.Stderr, "  pm group %s\n", cmd.usage)
+	}
+
+	return EXIT_USAGE
+}
+
+func runGroupList(env *Env, args []string) int {
+	flags := newFlagSet("group list")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	groups, err := project.LoadGroups()
+	if err != nil {
+		return fail(err)
+	}
+
+	projects, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tPROJECTS")
+	for _, g := range groups {
+		members, _ := project.GroupMembers(projects, g)
+		fmt.Fprintf(w, "%s\t%d\n", g.Name, len(members))
+	}
+	w.Flush()
+
+	return EXIT_OK
+}
+
+func runGroupShow(env *Env, args []string) int {
+	flags := newFlagSet("group show")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	g, code := findGroup(positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	projects, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Print(project.PrintGroupInfo(g, projects))
+
+	return EXIT_OK
+}
+
+func runGroupCreate(env *Env, args []string) int {
+	flags := newFlagSet("group create")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) < 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	ids, code := findProjectIDs(env, positional[1:])
+	if code != EXIT_OK {
+		return code
+	}
+
+	created, err := project.CreateGroup(positional[0], ids)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Created group %s with %d project(s)\n", created.Name, len(created.Projects))
+
+	return EXIT_OK
+}
+
+func runGroupAdd(env *Env, args []string) int {
+	return changeGroupMembers(env, "add", args)
+}
+
+func runGroupRemove(env *Env, args []string) int {
+	return changeGroupMembers(env, "remove", args)
+}
+
+// changeGroupMembers adds the projects after the group name to it, or
+// removes them from it.
+func changeGroupMembers(env *Env, name string, args []string) int {
+	flags := newFlagSet("group " + name)
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) < 2 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	ids, code := findProjectIDs(env, positional[1:])
+	if code != EXIT_OK {
+		return code
+	}
+
+	var changed project.Group
+	if name == "add" {
+		changed, err = project.SetGroupMembers(positional[0], ids, nil)
+	} else {
+		changed, err = project.SetGroupMembers(positional[0], nil, ids)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Group %s has %d project(s)\n", changed.Name, len(changed.Projects))
+
+	return EXIT_OK
+}
+
+func runGroupRename(env *Env, args []string) int {
+	flags := newFlagSet("group rename")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 2 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	if err := project.RenameGroup(positional[0], positional[1]); err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Renamed group %s to %s\n", positional[0], positional[1])
+
+	return EXIT_OK
+}
+
+func runGroupDelete(env *Env, args []string) int {
+	flags := newFlagSet("group delete")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	g, code := findGroup(positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	if err := project.DeleteGroup(g.Name); err != nil {
+		return fail(err)
+	}
+
+	fmt.Println("Deleted group", g.Name)
+
+	return EXIT_OK
+}
+
+func runGroupOpen(env *Env, args []string) int {
+	flags := newFlagSet("group open")
+	workspace := flags.Bool("workspace", false, "open one VS Code workspace with every project of the group")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	g, code := findGroup(positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	projects, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := project.OpenGroup(projects, g, *workspace); err != nil {
+		return fail(err)
+	}
+
+	return EXIT_OK
+}
+
+// runGroupWorkspace writes the .code-workspace file of a group and prints
+// its path, for editors that aren't started by pm.
+func runGroupWorkspace(env *Env, args []string) int {
+	flags := newFlagSet("group workspace")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	g, code := findGroup(positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	projects, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+
+	path, err := project.WriteWorkspace(projects, g)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Println(path)
+
+	return EXIT_OK
+}
+
+// findGroup looks up a group by name, like findProject.
+func findGroup(name string) (project.Group, int) {
+	groups, err := project.LoadGroups()
+	if err != nil {
+		return project.Group{}, fail(err)
+	}
+
+	i, err := project.FindGroup(groups, name)
+	if err != nil {
+		return project.Group{}, fail(err)
+	}
+
+	return groups[i], EXIT_OK
+}
+
+// findProjectIDs resolves every reference to the ID of a project.
+func findProjectIDs(env *Env, refs []string) ([]string, int) {
+	projects, err := env.Store.List()
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		p, err := project.FindProject(projects, ref)
+		if err != nil {
+			return nil, fail(err)
+		}
+		ids = append(ids, p.ID)
+	}
+
+	return ids, EXIT_OK
+}
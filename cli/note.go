@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// runNote prints the notes and checklist of a project, or changes them.
+// All changes of one call are saved together.
+func runNote(env *Env, args []string) int {
+	flags := newFlagSet("note")
+	edit := flags.Bool("edit", false, "edit the notes in $VISUAL or $EDITOR")
+	set := flags.String("set", "", "replace the notes, - reads them from stdin")
+	append_text := flags.String("append", "", "add a paragraph to the end of the notes")
+	clear := flags.Bool("clear", false, "remove the notes")
+	var add itemsValue
+	flags.Var(&add, "add", "add a checklist item, may be repeated")
+	toggle := flags.Int("toggle", 0, "mark checklist item `n` done, or open again")
+	remove := flags.Int("remove", 0, "remove checklist item `n`")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	note_flags := 0
+	for _, given := range []bool{*edit, *set != "", *append_text != "", *clear} {
+		if given {
+			note_flags++
+		}
+	}
+	if note_flags > 1 {
+		fmt.Fprintln(os.Stderr, "Pass only one of --edit, --set, --append and --clear")
+		return EXIT_USAGE
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	changed := note_flags > 0 || len(add) > 0 || *toggle != 0 || *remove != 0
+
+	switch {
+	case *edit:
+		if p.Notes, err = project.EditNotes(p.Notes); err != nil {
+			return fail(err)
+		}
+	case *set == "-":
+		notes, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fail(err)
+		}
+		p.Notes = string(notes)
+	case *set != "":
+		p.Notes = *set + "\n"
+	case *append_text != "":
+		if strings.TrimSpace(p.Notes) != "" {
+			p.Notes = strings.TrimRight(p.Notes, "\n") + "\n\n"
+		}
+		p.Notes += *append_text + "\n"
+	case *clear:
+		p.Notes = ""
+	}
+
+	for _, text := range add {
+		if err := project.AddChecklistItem(&p, text); err != nil {
+			return fail(err)
+		}
+	}
+
+	if *toggle != 0 {
+		if err := project.ToggleChecklistItem(&p, *toggle); err != nil {
+			return fail(err)
+		}
+	}
+
+	if *remove != 0 {
+		if err := project.RemoveChecklistItem(&p, *remove); err != nil {
+			return fail(err)
+		}
+	}
+
+	if changed {
+		if err := env.Store.Put(p); err != nil {
+			return fail(err)
+		}
+	}
+
+	fmt.Print(project.PrintNotes(p))
+
+	return EXIT_OK
+}
+
+// itemsValue is a flag that may be repeated, collecting every value.
+type itemsValue []string
+
+func (v *itemsValue) String() string {
+	return strings.Join(*v, ", ")
+}
+
+func (v *itemsValue) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
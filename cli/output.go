@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// outputFlags are the flags of commands that print projects.
+type outputFlags struct {
+	json   *bool
+	format *string
+	null   *bool
+}
+
+func addOutputFlags(flags *flag.FlagSet) *outputFlags {
+	output := &outputFlags{
+		json:   flags.Bool("json", false, "print the projects as a JSON array"),
+		format: flags.String("format", "", "print every project with a Go template, e.g. '{{.Name}}\\t{{.Path}}'"),
+		null:   new(bool),
+	}
+
+	flags.BoolVar(output.null, "0", false, "end every project with NUL instead of a newline, for xargs -0 and fzf --read0")
+	flags.BoolVar(output.null, "null", false, "same as -0")
+
+	return output
+}
+
+// write prints projects as the flags ask for, or with human if no flag was
+// given. Returns the exit code.
+func (o *outputFlags) write(projects []project.Project, human func() error) int {
+	if *o.json && (*o.format != "" || *o.null) {
+		fmt.Fprintln(os.Stderr, "--json can't be combined with --format or --null")
+		return EXIT_USAGE
+	}
+
+	var err error
+
+	switch {
+	case *o.json:
+		err = project.WriteProjectsJSON(os.Stdout, projects)
+	case *o.format != "" || *o.null:
+		terminator := "\n"
+		if *o.null {
+			terminator = "\x00"
+		}
+		err = project.FormatProjects(os.Stdout, projects, *o.format, terminator)
+	default:
+		err = human()
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	return EXIT_OK
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// runPick lets the user pick a project with the fuzzy picker and prints it,
+// the path unless --format says otherwise.
+func runPick(env *Env, args []string) int {
+	flags := newFlagSet("pick")
+	format := flags.String("format", "{{.Path}}", "Go template to print the picked project with")
+	null := flags.Bool("0", false, "end the output with NUL instead of a newline")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	projects, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+	projects = project.ActiveProjects(projects)
+
+	selected := pickProject(projects, "Pick a project")
+	if selected < 0 {
+		return EXIT_FAILURE
+	}
+
+	terminator := "\n"
+	if *null {
+		terminator = "\x00"
+	}
+
+	if err := project.FormatProjects(os.Stdout, projects[selected:selected+1], *format, terminator); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return EXIT_USAGE
+	}
+
+	return EXIT_OK
+}
@@ -0,0 +1,605 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	path_manager "github.com/yur4uwe/cmd-project-manager/manage_paths"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+func runAdd(env *Env, args []string) int {
+	flags := newFlagSet("add")
+	description := flags.String("description", "", "what the project is about")
+	parent := flags.String("dir", ".", "directory to create the project directory in")
+	tags := &tagsValue{}
+	flags.Var(tags, "tags", "comma separated tags")
+	fields := fieldsValue{}
+	flags.Var(fields, "field", "set custom field `name=value`, may be repeated")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+	name := positional[0]
+
+	if code := checkNameFree(env, name, ""); code != EXIT_OK {
+		return code
+	}
+
+	dir, err := filepath.Abs(*parent)
+	if err != nil {
+		return fail(err)
+	}
+
+	added, err := project.AddProject(env.Store, name, *description, filepath.ToSlash(dir), tags.value(), fields)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Added %s (%s) at %s\n", added.Name, added.ID, added.Path)
+
+	return EXIT_OK
+}
+
+func runLink(env *Env, args []string) int {
+	flags := newFlagSet("link")
+	name := flags.String("name", "", "project name (default the directory name)")
+	description := flags.String("description", "", "what the project is about")
+	tags := &tagsValue{}
+	flags.Var(tags, "tags", "comma separated tags")
+	fields := fieldsValue{}
+	flags.Var(fields, "field", "set custom field `name=value`, may be repeated")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) > 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	dir := "."
+	if len(positional) == 1 {
+		dir = positional[0]
+	}
+
+	if *name == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return fail(err)
+		}
+		*name = filepath.Base(abs)
+	}
+
+	if code := checkNameFree(env, *name, ""); code != EXIT_OK {
+		return code
+	}
+
+	linked, err := project.LinkProject(env.Store, *name, *description, dir, tags.value(), fields)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Linked %s (%s) at %s\n", linked.Name, linked.ID, linked.Path)
+
+	return EXIT_OK
+}
+
+func runList(env *Env, args []string) int {
+	flags := newFlagSet("list")
+	output := addOutputFlags(flags)
+	tags := &tagsValue{}
+	flags.Var(tags, "tag", "only list projects with this tag, may be repeated or comma separated")
+	archived := flags.Bool("archived", false, "list only the archived projects")
+	all := flags.Bool("all", false, "list the archived projects too")
+	var where itemsValue
+	flags.Var(&where, "where", "only list projects whose custom field matches `filter`, like client=acme or due<2025-01-01, may be repeated")
+	sort_key := flags.String("sort", "", "sort by name, path, created, opened (most first) or a custom field, a leading - reverses the order")
+	since := flags.String("since", "", "only list projects created or updated on or after `date`, YYYY-MM-DD or RFC 3339")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	filters, err := parseFieldFilters(where)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_USAGE
+	}
+
+	descending := strings.HasPrefix(*sort_key, "-")
+	by_opened := strings.ToLower(strings.TrimPrefix(*sort_key, "-")) == project.SORT_OPENED
+
+	query := project.ProjectQuery{Tags: tags.value(), ByOpened: by_opened}
+	if *since != "" {
+		if query.Since, err = parseSince(*since); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_USAGE
+		}
+	}
+
+	projects, err := queryProjects(env, query)
+	if err != nil {
+		return fail(err)
+	}
+	if *archived {
+		projects = project.ArchivedProjects(projects)
+	} else if !*all {
+		projects = project.ActiveProjects(projects)
+	}
+	projects = project.FilterByFields(projects, filters)
+
+	if query.ByOpened && descending {
+		// The query puts the most opened first, -opened the least
+		for i, j := 0, len(projects)-1; i < j; i, j = i+1, j-1 {
+			projects[i], projects[j] = projects[j], projects[i]
+		}
+	} else if *sort_key != "" && !query.ByOpened {
+		if err := project.SortProjects(projects, *sort_key); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_USAGE
+		}
+	}
+
+	schema := project.FieldSchema()
+
+	return output.write(projects, func() error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprint(w, "ID\tNAME\tPATH\tTAGS")
+		for _, def := range schema {
+			fmt.Fprintf(w, "\t%s", strings.ToUpper(def.Name))
+		}
+		fmt.Fprintln(w)
+		for _, p := range projects {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s", p.ID, p.Name, p.Path, strings.Join(p.Tags, ","))
+			for _, def := range schema {
+				fmt.Fprintf(w, "\t%s", p.Fields[def.Name])
+			}
+			fmt.Fprintln(w)
+		}
+		return w.Flush()
+	})
+}
+
+func runShow(env *Env, args []string) int {
+	flags := newFlagSet("show")
+	output := addOutputFlags(flags)
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	return output.write([]project.Project{p}, func() error {
+		_, err := fmt.Print(project.PrintProjectInfo(p))
+		return err
+	})
+}
+
+func runUpdate(env *Env, args []string) int {
+	flags := newFlagSet("update")
+	name := flags.String("name", "", "new name")
+	description := flags.String("description", "", "new description")
+	path := flags.String("path", "", "new project directory")
+	tags := &tagsValue{}
+	flags.Var(tags, "tags", "new comma separated tags, empty to remove them all")
+	fields := fieldsValue{}
+	flags.Var(fields, "field", "set custom field `name=value`, an empty value removes it, may be repeated")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	if *name == "" && *description == "" && *path == "" && !tags.set && len(fields) == 0 {
+		fmt.Fprintln(os.Stderr, "Nothing to update, pass --name, --description, --path, --tags or --field")
+		return EXIT_USAGE
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	if *name != "" {
+		if code := checkNameFree(env, *name, p.ID); code != EXIT_OK {
+			return code
+		}
+	}
+
+	if *path != "" {
+		abs, err := filepath.Abs(*path)
+		if err != nil {
+			return fail(err)
+		}
+		*path = filepath.ToSlash(abs)
+	}
+
+	if err := project.UpdateProject(env.Store, p.ID, *name, *description, *path, tags.value(), fields); err != nil {
+		return fail(err)
+	}
+
+	fmt.Println("Updated", p.Name)
+
+	return EXIT_OK
+}
+
+func runRemove(env *Env, args []string) int {
+	flags := newFlagSet("rm")
+	yes := flags.Bool("yes", false, "remove without asking")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	if !*yes {
+		fmt.Print(project.PrintProjectInfo(p))
+		if !askYesNo("Remove this project? Only the link is removed, not the directory.") {
+			fmt.Println("Nothing was removed.")
+			return EXIT_OK
+		}
+	}
+
+	if err := project.RemoveProject(env.Store, p.ID); err != nil {
+		return fail(err)
+	}
+
+	fmt.Println("Removed", p.Name)
+	if p.ArchiveFile != "" {
+		fmt.Println("Its archive is kept at", p.ArchiveFile)
+	}
+
+	return EXIT_OK
+}
+
+func runTags(env *Env, args []string) int {
+	flags := newFlagSet("tags")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	projects, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TAG\tPROJECTS")
+	for _, count := range project.CountTags(projects) {
+		fmt.Fprintf(w, "%s\t%d\n", count.Tag, count.Count)
+	}
+	w.Flush()
+
+	return EXIT_OK
+}
+
+func runPin(env *Env, args []string) int {
+	flags := newFlagSet("pin")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) > 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	if len(positional) == 0 {
+		projects, err := env.Store.List()
+		if err != nil {
+			return fail(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PIN\tNAME\tPATH")
+		for i, p := range project.PinnedProjects(projects) {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, p.Name, p.Path)
+		}
+		w.Flush()
+
+		return EXIT_OK
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	if err := project.PinProject(env.Store, p.ID); err != nil {
+		return fail(err)
+	}
+
+	fmt.Println("Pinned", p.Name)
+
+	return EXIT_OK
+}
+
+func runUnpin(env *Env, args []string) int {
+	flags := newFlagSet("unpin")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	if err := project.UnpinProject(env.Store, p.ID); err != nil {
+		return fail(err)
+	}
+
+	fmt.Println("Unpinned", p.Name)
+
+	return EXIT_OK
+}
+
+func runArchive(env *Env, args []string) int {
+	flags := newFlagSet("archive")
+	compress := flags.Bool("compress", false, "pack the directory into a tar.gz file in the data directory and delete it")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	archived, err := project.ArchiveProject(env.Store, p.ID, *compress)
+	if err != nil {
+		return fail(err)
+	}
+
+	if archived.ArchiveFile != "" {
+		fmt.Printf("Archived %s to %s\n", archived.Name, archived.ArchiveFile)
+	} else {
+		fmt.Println("Archived", archived.Name)
+	}
+
+	return EXIT_OK
+}
+
+func runRestore(env *Env, args []string) int {
+	flags := newFlagSet("restore")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	restored, err := project.RestoreProject(env.Store, p.ID)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Restored %s to %s\n", restored.Name, restored.Path)
+
+	return EXIT_OK
+}
+
+func runOpen(env *Env, args []string) int {
+	flags := newFlagSet("open")
+	explorer := flags.Bool("explorer", false, "open in the file explorer instead of VS Code")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	if *explorer {
+		err = project.OpenProjectInExplorer(p.Path)
+	} else {
+		path_manager.IncrementAccess(p.Path)
+		err = project.OpenProjectInVSCode(p.Path)
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	return EXIT_OK
+}
+
+func runPath(env *Env, args []string) int {
+	flags := newFlagSet("path")
+	copy_path := flags.Bool("copy", false, "also copy the path to the clipboard")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	p, code := findProject(env, positional[0])
+	if code != EXIT_OK {
+		return code
+	}
+
+	fmt.Println(p.Path)
+
+	if *copy_path {
+		if err := project.CopyProjectPath(p.Path); err != nil {
+			return fail(err)
+		}
+	}
+
+	return EXIT_OK
+}
+
+// findProject looks up a project by ID, name or ID prefix.
+func findProject(env *Env, ref string) (project.Project, int) {
+	projects, err := env.Store.List()
+	if err != nil {
+		return project.Project{}, fail(err)
+	}
+
+	p, err := project.FindProject(projects, ref)
+	if err != nil {
+		return project.Project{}, fail(err)
+	}
+
+	return p, EXIT_OK
+}
+
+// checkNameFree fails if a project other than the one with ID except is
+// already called name.
+func checkNameFree(env *Env, name, except string) int {
+	projects, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+
+	var others []project.Project
+	for _, p := range projects {
+		if p.ID != except {
+			others = append(others, p)
+		}
+	}
+
+	if !project.CheckDuplicateNames(&others, name) {
+		return fail(fmt.Errorf("a project called %q already exists", name))
+	}
+
+	return EXIT_OK
+}
+
+// tagsValue is a flag of tags that may be repeated and takes comma
+// separated lists.
+type tagsValue struct {
+	tags []string
+	set  bool
+}
+
+func (v *tagsValue) String() string {
+	return strings.Join(v.tags, ",")
+}
+
+func (v *tagsValue) Set(list string) error {
+	v.tags = append(v.tags, project.ParseTags(list)...)
+	v.set = true
+	return nil
+}
+
+// value returns the tags given, nil if the flag wasn't given at all and
+// empty if it was given empty.
+func (v *tagsValue) value() []string {
+	if !v.set {
+		return nil
+	}
+
+	if tags := project.NormalizeTags(v.tags); tags != nil {
+		return tags
+	}
+
+	return []string{}
+}
+
+// fieldsValue is a flag of custom fields given as name=value that may be
+// repeated. The values are checked against the schema when they are set
+// on a project.
+type fieldsValue map[string]string
+
+func (v fieldsValue) String() string {
+	var assignments []string
+	for name, value := range v {
+		assignments = append(assignments, name+"="+value)
+	}
+	sort.Strings(assignments)
+
+	return strings.Join(assignments, ", ")
+}
+
+func (v fieldsValue) Set(assignment string) error {
+	name, value, err := project.ParseFieldAssignment(assignment)
+	if err != nil {
+		return err
+	}
+
+	v[name] = value
+	return nil
+}
+
+// queryProjects answers query with the indexes of the backend if it has
+// them, or goes through every project otherwise.
+func queryProjects(env *Env, query project.ProjectQuery) ([]project.Project, error) {
+	if env.Query != nil {
+		return env.Query.QueryProjects(query)
+	}
+
+	projects, err := env.Store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	return project.QueryProjects(projects, query), nil
+}
+
+// parseSince reads the date of --since, a day in DATE_LAYOUT meaning its
+// start in local time, or an RFC 3339 timestamp.
+func parseSince(since string) (time.Time, error) {
+	if day, err := time.ParseInLocation(project.DATE_LAYOUT, since, time.Local); err == nil {
+		return day, nil
+	}
+
+	stamp, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--since %q is not a date like 2024-12-31 or 2024-12-31T15:04:05Z", since)
+	}
+
+	return stamp, nil
+}
+
+// parseFieldFilters parses the filters given with --where.
+func parseFieldFilters(where []string) ([]project.FieldFilter, error) {
+	var filters []project.FieldFilter
+	for _, filter := range where {
+		parsed, err := project.ParseFieldFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, parsed)
+	}
+
+	return filters, nil
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	display "github.com/yur4uwe/cmd-project-manager/display"
+	path_manager "github.com/yur4uwe/cmd-project-manager/manage_paths"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// The shell functions wrap pm so `pm cd` can change the directory of the
+// shell itself. %[1]s is the quoted path of the executable.
+var shellInits = map[string]string{
+	"bash": posixInit,
+	"zsh":  posixInit,
+	"fish": fishInit,
+}
+
+const posixInit = `pm() {
+    if [ "$1" = "cd" ]; then
+        shift
+        local dir
+        dir="$(command %[1]s cd "$@")" || return $?
+        [ -n "$dir" ] && builtin cd -- "$dir"
+    else
+        command %[1]s "$@"
+    fi
+}
+`
+
+const fishInit = `function pm
+    if test (count $argv) -gt 0; and test "$argv[1]" = cd
+        set -e argv[1]
+        set -l dir (command %[1]s cd $argv); or return $status
+        test -n "$dir"; and builtin cd -- $dir
+    else
+        command %[1]s $argv
+    end
+end
+`
+
+func runInit(env *Env, args []string) int {
+	if len(args) != 1 || shellInits[args[0]] == "" {
+		fmt.Fprintf(os.Stderr, "Usage: pm init <shell>\nShells: %s\n\n", strings.Join(shells, ", "))
+		fmt.Fprintln(os.Stderr, "Add this to your shell startup file, e.g. for bash:")
+		fmt.Fprintln(os.Stderr, `  eval "$(pm init bash)"`)
+		fmt.Fprintln(os.Stderr, "or for fish:")
+		fmt.Fprintln(os.Stderr, "  pm init fish | source")
+		return EXIT_USAGE
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf(shellInits[args[0]], shellQuote(executable))
+
+	return EXIT_OK
+}
+
+// runCd prints the directory of a project for the shell function to change
+// into. Without a project the picker is shown on stderr.
+func runCd(env *Env, args []string) int {
+	flags := newFlagSet("cd")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) > 1 {
+		fmt.Fprintln(os.Stderr, "Usage: pm cd [project]")
+		return EXIT_USAGE
+	}
+
+	var chosen project.Project
+
+	if len(positional) == 1 {
+		var code int
+		if chosen, code = findProject(env, positional[0]); code != EXIT_OK {
+			return code
+		}
+	} else {
+		projects, err := env.Store.List()
+		if err != nil {
+			return fail(err)
+		}
+		projects = project.ActiveProjects(projects)
+
+		selected := pickProject(projects, "Change to project")
+		if selected < 0 || selected >= len(projects) {
+			return EXIT_FAILURE
+		}
+		chosen = projects[selected]
+	}
+
+	if chosen.ArchiveFile != "" {
+		return fail(fmt.Errorf("%s is archived to %s, restore it first", chosen.Name, chosen.ArchiveFile))
+	}
+
+	path_manager.IncrementAccess(chosen.Path)
+	fmt.Println(chosen.Path)
+
+	return EXIT_OK
+}
+
+// pickProject runs the fuzzy picker drawn on the terminal itself, so it
+// stays out of what the shell captures. Without a terminal to read keys
+// from it asks line by line. Returns -1 if the user cancelled.
+func pickProject(projects []project.Project, header string) int {
+	defer display.OpenKeyboard()()
+
+	tty, err := os.OpenFile(TTY_PATH, os.O_WRONLY, 0)
+	if err != nil {
+		tty = os.Stderr
+	} else {
+		defer tty.Close()
+	}
+
+	display.SetOutput(tty)
+	defer display.SetOutput(os.Stdout)
+
+	return display.FuzzyPicker(projects, header)
+}
+
+// shellQuote quotes s for bash, zsh and fish alike.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
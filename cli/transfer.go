@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	display "github.com/yur4uwe/cmd-project-manager/display"
+	import_export "github.com/yur4uwe/cmd-project-manager/import_export"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+func runExport(env *Env, args []string) int {
+	flags := newFlagSet("export")
+	format := flags.String("format", import_export.FORMAT_JSON, "output format: "+strings.Join(import_export.Formats, ", "))
+	fields := flags.String("fields", "", "comma separated fields to export (default all: "+strings.Join(import_export.ColumnNames(), ",")+")")
+	output := flags.String("o", "-", "file to write to, - for stdout")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	projects, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+
+	var field_names []string
+	if *fields != "" {
+		field_names = strings.Split(*fields, ",")
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fail(err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := import_export.Export(w, projects, *format, field_names); err != nil {
+		return fail(err)
+	}
+
+	return EXIT_OK
+}
+
+func runImport(env *Env, args []string) int {
+	flags := newFlagSet("import")
+	format := flags.String("format", "", "input format: "+strings.Join(import_export.Formats, ", ")+" (default from the file extension)")
+	mapping := flags.String("map", "", "map source fields to project fields, e.g. \"Project Name=Name,Folder=Path\"")
+	dry_run := flags.Bool("dry-run", false, "only show what would be added, updated and skipped")
+	yes := flags.Bool("yes", false, "apply without asking")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+	source := positional[0]
+
+	if *format == "" {
+		detected, ok := import_export.FormatFromPath(source)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Can't tell the format of", source, "- use --format")
+			return EXIT_USAGE
+		}
+		*format = detected
+	}
+
+	field_mapping, err := import_export.ParseMapping(*mapping)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_USAGE
+	}
+
+	var r io.Reader = os.Stdin
+	if source != "-" {
+		file, err := os.Open(source)
+		if err != nil {
+			return fail(err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	records, err := import_export.Parse(r, *format)
+	if err != nil {
+		return fail(err)
+	}
+
+	incoming, record_errs, unmapped := import_export.MapRecords(records, field_mapping)
+	if len(unmapped) > 0 {
+		fmt.Printf("Ignoring fields without a mapping or that can't be imported: %s\n\n", strings.Join(unmapped, ", "))
+	}
+
+	existing, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+
+	actions := import_export.PlanImport(existing, incoming, record_errs)
+	printImportPlan(actions)
+
+	if *dry_run {
+		return EXIT_OK
+	}
+
+	if !*yes {
+		if source == "-" {
+			fmt.Fprintln(os.Stderr, "Reading the import from stdin, pass --yes to apply it")
+			return EXIT_USAGE
+		}
+		if !askYesNo("Apply these changes?") {
+			fmt.Println("Nothing was imported.")
+			return EXIT_OK
+		}
+	}
+
+	added, updated, err := import_export.ApplyImport(env.Store, actions)
+	fmt.Printf("Added %d and updated %d project(s).\n", added, updated)
+	if err != nil {
+		return fail(err)
+	}
+
+	return EXIT_OK
+}
+
+func printImportPlan(actions []import_export.ImportAction) {
+	counts := map[string]int{}
+	for _, action := range actions {
+		counts[action.Action]++
+		fmt.Println("  " + action.String())
+	}
+
+	fmt.Printf("\n%d to add, %d to update, %d to skip.\n",
+		counts[import_export.ACTION_ADD], counts[import_export.ACTION_UPDATE], counts[import_export.ACTION_SKIP])
+}
+
+// askYesNo asks question on stdout and reads the answer from stdin.
+func askYesNo(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, _ := display.ReadLine()
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+func runImportFrom(env *Env, args []string) int {
+	flags := newFlagSet("import-from")
+	dry_run := flags.Bool("dry-run", false, "only show what would be added and skipped")
+	yes := flags.Bool("yes", false, "import every new project without asking")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil || len(positional) < 1 || len(positional) > 2 {
+		flags.Usage()
+		fmt.Fprintln(os.Stderr, "Sources:", strings.Join(import_export.Sources, ", "))
+		return EXIT_USAGE
+	}
+
+	source := positional[0]
+	path := ""
+	if len(positional) == 2 {
+		path = positional[1]
+	}
+
+	candidates, err := import_export.ReadSource(source, path)
+	if err != nil {
+		return fail(err)
+	}
+
+	existing, err := env.Store.List()
+	if err != nil {
+		return fail(err)
+	}
+
+	actions := import_export.PlanSourceImport(existing, candidates)
+
+	var picked []bool
+	switch {
+	case *dry_run:
+		printImportPlan(actions)
+		return EXIT_OK
+	case *yes:
+		picked = make([]bool, len(actions))
+		for i := range picked {
+			picked[i] = true
+		}
+	case path == "-":
+		fmt.Fprintln(os.Stderr, "Reading the import from stdin, pass --yes to apply it")
+		return EXIT_USAGE
+	default:
+		close_keyboard := display.OpenKeyboard()
+		var ok bool
+		picked, ok = display.SelectImports(actions)
+		close_keyboard()
+		if !ok {
+			fmt.Println("Nothing was imported.")
+			return EXIT_OK
+		}
+	}
+
+	var chosen []import_export.ImportAction
+	var history []import_export.Candidate
+
+	for i, action := range actions {
+		if action.Action == import_export.ACTION_ADD && picked[i] {
+			chosen = append(chosen, action)
+			history = append(history, candidates[i])
+		} else if action.Action == import_export.ACTION_SKIP && findByPath(existing, action.Project.Path) {
+			// Usage counts of projects that are already registered are still worth keeping
+			history = append(history, candidates[i])
+		}
+	}
+
+	added, _, err := import_export.ApplyImport(env.Store, chosen)
+	fmt.Printf("Added %d project(s).\n", added)
+	if err != nil {
+		return fail(err)
+	}
+
+	import_export.ImportHistory(history)
+
+	return EXIT_OK
+}
+
+func findByPath(projects []project.Project, path string) bool {
+	for _, p := range projects {
+		if filepath.Clean(p.Path) == filepath.Clean(path) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package cli
+
+// TTY_PATH is the terminal of the process, even when stdout is redirected.
+const TTY_PATH = "/dev/tty"
@@ -0,0 +1,6 @@
+//go:build windows
+
+package cli
+
+// TTY_PATH is the console of the process, even when stdout is redirected.
+const TTY_PATH = "CONOUT$"
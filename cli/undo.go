@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+func runUndo(env *Env, args []string) int {
+	return runJournalStep(env, args, "undo", "Undid")
+}
+
+func runRedo(env *Env, args []string) int {
+	return runJournalStep(env, args, "redo", "Redid")
+}
+
+func runJournalStep(env *Env, args []string, name, done string) int {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Usage: pm %s\n", name)
+		return EXIT_USAGE
+	}
+
+	journaled, ok := env.Store.(*project.JournaledStore)
+	if !ok {
+		return fail(fmt.Errorf("%s: the store keeps no journal", name))
+	}
+
+	step := journaled.Undo
+	if name == "redo" {
+		step = journaled.Redo
+	}
+
+	entry, err := step()
+	if errors.Is(err, project.ErrNothingToUndo) || errors.Is(err, project.ErrNothingToRedo) {
+		fmt.Println("Nothing to " + name)
+		return EXIT_FAILURE
+	} else if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("%s: %s\n", done, entry)
+
+	return EXIT_OK
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+const (
+	BACKEND_JSON   = "json"
+	BACKEND_SQLITE = "sqlite"
+)
+
+type Config struct {
+	// Backend selects where projects and path history are stored,
+	// BACKEND_JSON or BACKEND_SQLITE.
+	Backend string `json:"backend"`
+
+	ProjectsFile string `json:"projects_file"`
+	HistoryFile  string `json:"history_file"`
+	SQLiteFile   string `json:"sqlite_file"`
+	JournalFile  string `json:"journal_file"`
+
+	SnapshotDir string `json:"snapshot_dir"`
+	// SnapshotEveryLaunches takes a snapshot every that many interactive
+	// launches, 0 turns scheduled snapshots off.
+	SnapshotEveryLaunches int `json:"snapshot_every_launches"`
+	// SnapshotKeep is how many automatic snapshots are kept, 0 keeps all.
+	SnapshotKeep int `json:"snapshot_keep"`
+
+	// ArchiveDir keeps the compressed directories of archived projects.
+	ArchiveDir string `json:"archive_dir"`
+
+	// Fields are the custom fields projects can carry.
+	Fields []project.FieldDef `json:"fields"`
+
+	// GroupsFile keeps the project groups, WorkspaceDir the .code-workspace
+	// files generated for them.
+	GroupsFile   string `json:"groups_file"`
+	WorkspaceDir string `json:"workspace_dir"`
+	// Editor is the command projects and groups are opened with.
+	Editor string `json:"editor"`
+}
+
+func Default() Config {
+	return Config{
+		Backend:      BACKEND_JSON,
+		ProjectsFile: ".projects.json",
+		HistoryFile:  ".directory_history.json",
+		SQLiteFile:   ".projects.db",
+		JournalFile:  ".projects.journal",
+
+		SnapshotDir:           ".snapshots",
+		SnapshotEveryLaunches: 10,
+		SnapshotKeep:          20,
+
+		ArchiveDir: ".archives",
+
+		GroupsFile:   ".groups.json",
+		WorkspaceDir: ".workspaces",
+		Editor:       "code",
+	}
+}
+
+// Load reads the configuration at path on top of the defaults. A missing
+// file means the defaults. PM_BACKEND overrides the configured backend.
+// Relative paths in it are left relative, see InDataDir.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	file, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return cfg, fmt.Errorf("read config file: %w", err)
+	}
+
+	if err == nil {
+		if err := json.Unmarshal(file, &cfg); err != nil {
+			return cfg, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+
+	if backend := os.Getenv("PM_BACKEND"); backend != "" {
+		cfg.Backend = backend
+	}
+
+	if cfg.Backend != BACKEND_JSON && cfg.Backend != BACKEND_SQLITE {
+		return cfg, fmt.Errorf("unknown backend %q, expected %q or %q", cfg.Backend, BACKEND_JSON, BACKEND_SQLITE)
+	}
+
+	if err := project.CheckFieldSchema(cfg.Fields); err != nil {
+		return cfg, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	file_utils "github.com/yur4uwe/cmd-project-manager/file_utils"
+)
+
+// LegacyConfigFile is where versions before the XDG directories read the
+// configuration from, relative to the working directory.
+const LegacyConfigFile = ".pm_config.json"
+
+// LEGACY_CHECKED_FILE in the state directory records that the user was
+// already asked about moving legacy files, so they are only asked once.
+const LEGACY_CHECKED_FILE = "legacy_checked"
+
+// Legacy is a registry left behind by a version that kept its files in the
+// working directory or next to the executable.
+type Legacy struct {
+	Dir string
+	// Files are the names of the files and directories found in Dir.
+	Files []string
+}
+
+/*
+FindLegacy looks for a legacy registry in each of dirs, in order.
+
+Parameters:
+- dirs: The directories to look in, usually the working directory and the one of the executable.
+
+Returns:
+- Legacy: The first directory holding a projects file or database, with every legacy file in it.
+- bool: false if none was found.
+*/
+func FindLegacy(dirs ...string) (Legacy, bool) {
+	for _, dir := range dirs {
+		cfg, err := Load(filepath.Join(dir, LegacyConfigFile))
+		if err != nil {
+			cfg = Default()
+		}
+
+		legacy := Legacy{Dir: dir}
+		found_registry := false
+
+		if exists(filepath.Join(dir, LegacyConfigFile)) {
+			legacy.Files = append(legacy.Files, LegacyConfigFile)
+		}
+
+		for _, path := range cfg.paths() {
+			if *path == "" || filepath.IsAbs(*path) || !exists(filepath.Join(dir, *path)) {
+				continue
+			}
+
+			legacy.Files = append(legacy.Files, *path)
+			if *path == cfg.ProjectsFile || *path == cfg.SQLiteFile {
+				found_registry = true
+			}
+		}
+
+		if found_registry {
+			return legacy, true
+		}
+	}
+
+	return Legacy{}, false
+}
+
+/*
+CopyInto copies the legacy files into the new directories. The
+configuration goes to the config directory, everything else to the data
+directory under the same name. Files that already exist there are not
+overwritten. The legacy files are left where they are.
+
+Parameters:
+- dirs: The directories to copy into.
+
+Returns:
+- error: An error if a file could not be copied.
+*/
+func (l Legacy) CopyInto(dirs Dirs) error {
+	for _, name := range l.Files {
+		source := filepath.Join(l.Dir, name)
+
+		target := filepath.Join(dirs.Data, name)
+		if name == LegacyConfigFile {
+			target = dirs.ConfigFile()
+		}
+
+		if exists(target) {
+			continue
+		}
+
+		if err := copyTree(source, target); err != nil {
+			return fmt.Errorf("copy %s: %w", source, err)
+		}
+	}
+
+	return nil
+}
+
+// copyTree copies a file, or a directory with everything in it.
+func copyTree(source, target string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		destination := filepath.Join(target, relative)
+
+		if entry.IsDir() {
+			return os.MkdirAll(destination, 0755)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+			return err
+		}
+
+		return file_utils.WriteFileAtomic(destination, data, 0644)
+	})
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
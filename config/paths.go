@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// APP_DIR is the name of the directory the manager keeps its files in,
+// inside each of the base directories.
+const APP_DIR = "project-manager"
+
+// ConfigFileName is the name of the configuration file in the config directory.
+const ConfigFileName = "config.json"
+
+// LogFileName is the name of the log file in the state directory.
+const LogFileName = "log.txt"
+
+/*
+Dirs are the directories the manager keeps its files in.
+
+Each is resolved in this order:
+- the --data-dir flag, for the data directory only
+- PM_DATA_DIR, PM_CONFIG_DIR and PM_STATE_DIR
+- $XDG_DATA_HOME, $XDG_CONFIG_HOME and $XDG_STATE_HOME, followed by APP_DIR
+- ~/.local/share, ~/.config and ~/.local/state, followed by APP_DIR. On Windows %LOCALAPPDATA% and %APPDATA% instead.
+*/
+type Dirs struct {
+	// Config holds the configuration file.
+	Config string
+	// Data holds the registry, the path history, the journal and the snapshots.
+	Data string
+	// State holds the log.
+	State string
+}
+
+// ResolveDirs works out the directories, data_dir being the --data-dir flag
+// or "" if it was not given.
+func ResolveDirs(data_dir string) Dirs {
+	home, _ := os.UserHomeDir()
+
+	dirs := Dirs{
+		Config: resolveDir("PM_CONFIG_DIR", "XDG_CONFIG_HOME", filepath.Join(home, ".config"), "APPDATA"),
+		Data:   resolveDir("PM_DATA_DIR", "XDG_DATA_HOME", filepath.Join(home, ".local", "share"), "LOCALAPPDATA"),
+		State:  resolveDir("PM_STATE_DIR", "XDG_STATE_HOME", filepath.Join(home, ".local", "state"), "LOCALAPPDATA"),
+	}
+
+	if data_dir != "" {
+		dirs.Data = data_dir
+	}
+
+	return dirs
+}
+
+func resolveDir(override_env, xdg_env, fallback, windows_env string) string {
+	if dir := os.Getenv(override_env); dir != "" {
+		return dir
+	}
+
+	if base := os.Getenv(xdg_env); filepath.IsAbs(base) {
+		return filepath.Join(base, APP_DIR)
+	}
+
+	if runtime.GOOS == "windows" {
+		if base := os.Getenv(windows_env); base != "" {
+			return filepath.Join(base, APP_DIR)
+		}
+	}
+
+	return filepath.Join(fallback, APP_DIR)
+}
+
+// Create makes every directory that doesn't exist yet.
+func (d Dirs) Create() error {
+	for _, dir := range []string{d.Config, d.Data, d.State} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d Dirs) ConfigFile() string {
+	return filepath.Join(d.Config, ConfigFileName)
+}
+
+func (d Dirs) LogFile() string {
+	return filepath.Join(d.State, LogFileName)
+}
+
+// InDataDir makes the relative file and directory paths of the configuration
+// relative to the data directory. Absolute paths are left as they are.
+func (c *Config) InDataDir(data_dir string) {
+	for _, path := range c.paths() {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(data_dir, *path)
+		}
+	}
+}
+
+// paths returns the fields of the configuration that hold a path.
+func (c *Config) paths() []*string {
+	return []*string{&c.ProjectsFile, &c.HistoryFile, &c.SQLiteFile, &c.JournalFile, &c.SnapshotDir, &c.ArchiveDir, &c.GroupsFile, &c.WorkspaceDir}
+}
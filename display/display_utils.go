@@ -1,19 +1,33 @@
 package display
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/eiannone/keyboard"
+	path_manager "github.com/yur4uwe/cmd-project-manager/manage_paths"
 	project "github.com/yur4uwe/cmd-project-manager/project_utils"
 )
 
+// output is where menus are drawn.
+var output io.Writer = os.Stdout
+
+// SetOutput makes menus draw to w, so they stay out of output that is
+// piped or captured.
+func SetOutput(w io.Writer) {
+	output = w
+}
+
 func Clear() {
-	fmt.Print("\033[H\033[2J")
+	if line_mode {
+		return
+	}
+
+	fmt.Fprint(output, "\033[H\033[2J")
 }
 
 func getExecutablePath() (string, error) {
@@ -28,12 +42,36 @@ func getExecutablePath() (string, error) {
 	return strings.ReplaceAll(dir, "\\", "/"), err
 }
 
+// PrintCompressedProjectList lets the user choose one of projects, with the
+// pinned ones first. Returns the index in projects, or what ChoiceMenu
+// returns if nothing was chosen.
 func PrintCompressedProjectList(projects []project.Project) int {
-	var projects_slice = strings.Split(project.PrintCompressedProjectsSlice(projects), "\n")[:len(projects)]
+	order := project.PinnedFirst(projects)
+	options := compressedOptions(projects, order)
 
 	defer Clear()
 
-	return ChoiceMenu(projects_slice, "Projects:\n", "  No projects found.")
+	selected := ChoiceMenu(options, "Projects:\n", "  No projects found.")
+	if selected < 0 || selected >= len(order) {
+		return selected
+	}
+
+	return order[selected]
+}
+
+// compressedOptions is a line for every project in order, pinned ones
+// marked with a star.
+func compressedOptions(projects []project.Project, order []int) []string {
+	options := make([]string, len(order))
+	for i, index := range order {
+		mark := "  "
+		if projects[index].Pinned != 0 {
+			mark = "* "
+		}
+		options[i] = mark + strings.TrimSuffix(project.PrintCompressedProjectsSlice(projects[index:index+1]), "\n")
+	}
+
+	return options
 }
 
 func isValidPath(path string) bool {
@@ -60,32 +98,34 @@ func isValidPath(path string) bool {
 }
 
 func GetMostRecentPaths() []string {
-
-	// Get the most recent paths from the .directory_history.json file
-	// and return them as a slice of strings
-	// If the file does not exist, return an empty slice
-	// If the file exists but is empty, return an empty slice
-	// If the file exists and contains paths, return them as a slice of strings
-
-	// Read the file
-	file, err := os.ReadFile(".directory_history.json")
-	if err != nil {
-		log.Println("GetMostRecentPaths: Error while reading path history file:", err)
+	paths := path_manager.GetMostRecentPaths()
+	if paths == nil {
 		return []string{}
 	}
 
-	// Unmarshal the file
+	return paths
+}
+
+// suggestedPaths is the paths of the pinned projects followed by the most
+// recent ones.
+func suggestedPaths(pinned []project.Project) []string {
 	var paths []string
-	err = json.Unmarshal(file, &paths)
-	if err != nil {
-		log.Println("GetMostRecentPaths: Error while unmarshaling JSON file", err)
-		return []string{}
+	seen := make(map[string]bool)
+
+	for _, p := range pinned {
+		if !seen[p.Path] {
+			seen[p.Path] = true
+			paths = append(paths, p.Path)
+		}
 	}
 
-	// Return the 5 most recent paths
-	if len(paths) > 5 {
-		return paths[:5]
+	for _, path := range GetMostRecentPaths() {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
 	}
+
 	return paths
 }
 
@@ -117,6 +157,35 @@ func MatchFoldersInPath(valid_path string, name_to_match string) []string {
 	return folders
 }
 
+func waitForEnter() {
+	if line_mode {
+		return
+	}
+
+	fmt.Println("Press Enter to continue...")
+	for {
+		_, key, err := keyboard.GetKey()
+		if err != nil {
+			log.Fatal("Error while getting keyboard key: ", err)
+		}
+		if key == keyboard.KeyEnter || key == keyboard.KeyEsc {
+			break
+		}
+	}
+}
+
+// confirm shows prompt and reports whether it was answered with y
+func confirm(prompt string) bool {
+	fmt.Println(prompt)
+
+	char, _, err := readKey()
+	if err != nil {
+		log.Fatal("Error while getting keyboard key: ", err)
+	}
+
+	return char == 'y' || char == 'Y'
+}
+
 func arrayContainsAtLeastOneKey(array []keyboard.Key, args ...keyboard.Key) bool {
 	for i := 0; i < len(array); i++ {
 		for j := 0; j < len(args); j++ {
@@ -1,10 +1,9 @@
 package display
 
 import (
-	"bufio"
 	"fmt"
 	"log"
-	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/eiannone/keyboard"
@@ -12,66 +11,305 @@ import (
 	project "github.com/yur4uwe/cmd-project-manager/project_utils"
 )
 
-// (int) Returns the index of the selected option
-func MainMenu() int {
-	var display_string string = "Main Menu\n"
+// MainMenu returns PINNED_RESULT - i when the hotkey of the i-th pinned
+// project is pressed
+const PINNED_RESULT = -10
+
+// MAX_PINNED_HOTKEYS is how many pinned projects get a number hotkey
+const MAX_PINNED_HOTKEYS = 9
+
+// (int) Returns the index of the selected option,
+// -3 for undo ('u'), -4 for redo (Ctrl+R) and PINNED_RESULT - i for
+// the number hotkey of the i-th pinned project
+func MainMenu(pinned []project.Project) int {
+	var display_string string = "Main Menu  (u: undo, Ctrl+R: redo)\n"
 
 	options := []string{
 		"Add Project",
 		"Update Project",
 		"Remove Project",
 		"List Projects",
+		"Tags",
+		"Groups",
+		"Archived",
+		"Snapshots",
 		"Exit",
 	}
 
-	return ChoiceMenu(options, display_string, "", "Q", "q")
+	hotkeys := []Hotkey{
+		{Char: 'u', Result: -3},
+		{Key: keyboard.KeyCtrlR, Line: "r", Result: -4},
+	}
+
+	if len(pinned) > MAX_PINNED_HOTKEYS {
+		pinned = pinned[:MAX_PINNED_HOTKEYS]
+	}
+
+	if len(pinned) > 0 {
+		display_string += "\nPinned\n"
+		for i, p := range pinned {
+			hotkey := Hotkey{Char: rune('1' + i), Line: fmt.Sprintf("p%d", i+1), Result: PINNED_RESULT - i}
+			display_string += fmt.Sprintf("  %s  %s\n", hotkey.label(), p.Name)
+			hotkeys = append(hotkeys, hotkey)
+		}
+		display_string += "\n"
+	}
+
+	return HotkeyMenu(options, display_string, "", hotkeys, "Q", "q")
 }
 
-// (void) Lists Projects
-func ProjectsList(projects []project.Project) {
-	var selected = PrintCompressedProjectList(projects)
+// (error) Lists Projects
+func ProjectsList(store project.ProjectStore) error {
+	return TaggedProjectsList(store, nil)
+}
 
-	if selected < 0 || selected >= len(projects) {
-		return
+// (error) Lists the projects carrying every one of tags, 't' changes the
+// tags filtered by, 'f' the custom field filters and 's' the sort order
+func TaggedProjectsList(store project.ProjectStore, tags []string) error {
+	projects, err := store.List()
+	if err != nil {
+		return err
 	}
 
-	Clear()
+	var selected int
+	var shown []project.Project
+	var filters []project.FieldFilter
+	var sort_key string
+
+	// Without custom fields there is nothing to filter by
+	has_fields := len(project.FieldSchema()) > 0
+
+	hotkeys := []Hotkey{{Char: 't', Result: -3}}
+	if has_fields {
+		hotkeys = append(hotkeys, Hotkey{Char: 'f', Result: -4})
+	}
+	hotkeys = append(hotkeys, Hotkey{Char: 's', Result: -5})
+
+	for {
+		shown = project.FilterByFields(project.FilterByTags(project.ActiveProjects(projects), tags), filters)
+
+		if sort_key == "" {
+			shown = project.SortPinned(shown)
+		} else if err := project.SortProjects(shown, sort_key); err != nil {
+			return err
+		}
+
+		// shown is in display order already
+		order := make([]int, len(shown))
+		for i := range order {
+			order[i] = i
+		}
+		options := compressedOptions(shown, order)
+
+		header := "Projects"
+		if len(tags) > 0 {
+			header += " tagged " + strings.Join(tags, ", ")
+		}
+		if len(filters) > 0 {
+			header += " where " + describeFilters(filters)
+		}
+		if sort_key != "" {
+			header += " by " + sort_key
+		}
+		header += ":  (t: filter by tags"
+		if has_fields {
+			header += ", f: filter by fields"
+		}
+		header += ", s: sort)\n"
+
+		selected = HotkeyMenu(options, header, "  No projects found.", hotkeys)
+		Clear()
+
+		switch selected {
+		case -3:
+			tag_list, err := readInputWithCancel("Tags to filter by (comma separated, empty for all):", keyboard.KeyEsc)
+			if err == nil {
+				tags = project.ParseTags(tag_list)
+			}
+			continue
+		case -4:
+			filters = readFieldFilters(filters)
+			continue
+		case -5:
+			sort_key = readSortKey(sort_key)
+			continue
+		}
+
+		break
+	}
+
+	if selected < 0 || selected >= len(shown) {
+		return nil
+	}
+
+	return ProjectOptions(store, shown[selected])
+}
+
+// (error) Shows a project and what can be done with it
+func ProjectOptions(store project.ProjectStore, p project.Project) error {
+	pin_option := "Pin to Main Menu"
+	if p.Pinned != 0 {
+		pin_option = "Unpin from Main Menu"
+	}
 
-	header := project.PrintProjectInfo(projects[selected]) + "\nProject Options\n"
-	options := []string{"Open in VS Code", "Open in File Explorer", "Copy Path to Projects Directory", "Back"}
+	header := project.PrintProjectInfo(p) + "\nProject Options\n"
+	options := []string{"Open in VS Code", "Open in File Explorer", "Copy Path to Projects Directory", "Notes", pin_option, "Archive", "Back"}
 
 	do_next := ChoiceMenu(options, header, "", "B", "b")
 
+	var err error
 	switch do_next {
-	case -1, -2, 3:
-		return
+	case -1, -2, 6:
+		return nil
 	case 0:
-		path_manager.IncrementAccess(projects[selected].Path)
-		project.OpenProjectInVSCode(projects[selected].Path)
+		path_manager.IncrementAccess(p.Path)
+		err = project.OpenProjectInVSCode(p.Path)
 	case 1:
-		project.OpenProjectInExplorer(projects[selected].Path)
+		err = project.OpenProjectInExplorer(p.Path)
 	case 2:
-		project.CopyProjectPath(projects[selected].Path)
+		err = project.CopyProjectPath(p.Path)
+	case 3:
+		Clear()
+		return NotesScreen(store, p.ID)
+	case 4:
+		if p.Pinned != 0 {
+			return project.UnpinProject(store, p.ID)
+		}
+		return project.PinProject(store, p.ID)
+	case 5:
+		Clear()
+		return archiveProject(store, p)
+	}
+
+	if err != nil {
+		return err
 	}
 
-	fmt.Println("Press Enter to continue...")
+	waitForEnter()
+
+	return nil
+}
+
+// (error) Archives the project, asking whether to compress its directory
+func archiveProject(store project.ProjectStore, p project.Project) error {
+	compress := confirm(project.PrintProjectInfo(p) +
+		"\nAlso pack the directory into a tar.gz archive and delete it? (y/n)\n" +
+		"It is unpacked back to the same path when the project is restored.")
+	Clear()
+
+	archived, err := project.ArchiveProject(store, p.ID, compress)
+	if err != nil {
+		return err
+	}
+
+	if archived.ArchiveFile != "" {
+		ShowMessage(fmt.Sprintf("Archived %s to %s", archived.Name, archived.ArchiveFile))
+	} else {
+		ShowMessage(fmt.Sprintf("Archived %s", archived.Name))
+	}
+
+	return nil
+}
+
+// (error) Lists the archived projects to restore or remove them
+func ArchivedScreen(store project.ProjectStore) error {
 	for {
-		_, key, err := keyboard.GetKey()
+		projects, err := store.List()
 		if err != nil {
-			log.Fatal("Error while getting keyboard key: ", err)
+			return err
 		}
-		if key == keyboard.KeyEnter || key == keyboard.KeyEsc {
-			break
+
+		archived := project.ArchivedProjects(projects)
+
+		options := make([]string, len(archived))
+		for i, p := range archived {
+			options[i] = fmt.Sprintf("%s (%s), archived %s", p.Name, p.Path, p.Archived)
+			if p.ArchiveFile != "" {
+				options[i] += " [tar.gz]"
+			}
+		}
+
+		selected := ChoiceMenu(options, "Archived Projects:\n", "  No archived projects.", "B", "b")
+		Clear()
+
+		if selected < 0 || selected >= len(archived) {
+			return nil
 		}
+
+		header := project.PrintProjectInfo(archived[selected]) + "\nArchived Project Options\n"
+		do_next := ChoiceMenu([]string{"Restore", "Remove", "Back"}, header, "", "B", "b")
+		Clear()
+
+		switch do_next {
+		case 0:
+			restored, err := project.RestoreProject(store, archived[selected].ID)
+			if err != nil {
+				return err
+			}
+			ShowMessage(fmt.Sprintf("Restored %s to %s", restored.Name, restored.Path))
+		case 1:
+			buffer := project.PrintProjectInfo(archived[selected]) +
+				"\nAre you sure you want to remove this project? (y/n)\n"
+			if archived[selected].ArchiveFile != "" {
+				buffer += "\n**The archive file is kept**"
+			}
+			if confirm(buffer) {
+				if err := project.RemoveProject(store, archived[selected].ID); err != nil {
+					return err
+				}
+			}
+		}
+		Clear()
 	}
 }
 
-// Returns mutated projects slice
-func RemoveProject(projects []project.Project) []project.Project {
+// (error) Shows how many projects carry every tag, picking a tag lists them
+func TagsScreen(store project.ProjectStore) error {
+	for {
+		projects, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		counts := project.CountTags(project.ActiveProjects(projects))
+
+		width := 0
+		for _, count := range counts {
+			if len(count.Tag) > width {
+				width = len(count.Tag)
+			}
+		}
+
+		options := make([]string, len(counts))
+		for i, count := range counts {
+			options[i] = fmt.Sprintf("%-*s  %s %d", width, count.Tag, strings.Repeat("#", count.Count), count.Count)
+		}
+
+		selected := ChoiceMenu(options, "Tags:\n", "  No project has tags yet.", "B", "b")
+		Clear()
+
+		if selected < 0 || selected >= len(counts) {
+			return nil
+		}
+
+		if err := TaggedProjectsList(store, []string{counts[selected].Tag}); err != nil {
+			return err
+		}
+		Clear()
+	}
+}
+
+// Removes the selected project from the store
+func RemoveProject(store project.ProjectStore) error {
+	projects, err := store.List()
+	if err != nil {
+		return err
+	}
+
 	var selected = PrintCompressedProjectList(projects)
 
 	if selected < 0 || selected >= len(projects) {
-		return projects
+		return nil
 	}
 
 	buffer := project.PrintProjectInfo(projects[selected]) +
@@ -81,67 +319,81 @@ func RemoveProject(projects []project.Project) []project.Project {
 
 	fmt.Println(buffer)
 
-	var char, key, err = keyboard.GetKey()
+	var char, key, key_err = readKey()
 
-	if err != nil {
-		log.Fatal("Error while getting keyboard key: ", err)
+	if key_err != nil {
+		log.Fatal("Error while getting keyboard key: ", key_err)
 	}
 
-	if key == keyboard.KeyEnter || char == 'y' || char == 'Y' {
-		path_manager.RemovePath(projects[selected].Path)
-		projects = append(projects[:selected], projects[selected+1:]...)
-		return projects
+	// A blank line is Enter in line mode, so piped input has to say y
+	if (key == keyboard.KeyEnter && !line_mode) || char == 'y' || char == 'Y' {
+		return project.RemoveProject(store, projects[selected].ID)
 	}
 
-	return projects
+	return nil
 }
 
-// Returns mutated projects slice
-func UpdateProject(projects []project.Project) []project.Project {
+// Updates the selected project in the store
+func UpdateProject(store project.ProjectStore) error {
+	projects, err := store.List()
+	if err != nil {
+		return err
+	}
+
 	var selected = PrintCompressedProjectList(projects)
 
 	if selected < 0 || selected >= len(projects) {
-		return projects
+		return nil
 	}
 
 	fmt.Println(project.PrintProjectInfo(projects[selected]))
 
-	reader := bufio.NewReader(os.Stdin)
-
 	fmt.Println("Update Project fields(leave empty to keep current value):")
 
 	fmt.Printf("Old Name: %s\n", projects[selected].Name)
 	fmt.Print("Name: ")
-	name, _ := reader.ReadString('\n')
-	if strings.TrimSpace(name) != "" {
-		name = strings.TrimSpace(name)
-		projects[selected].Name = name
-	}
+	name, _ := ReadLine()
+	name = strings.TrimSpace(name)
 
 	fmt.Printf("Old Description: %s\n", projects[selected].Description)
 	fmt.Print("Description: ")
-	description, _ := reader.ReadString('\n')
-	if strings.TrimSpace(description) != "" {
-		description = strings.TrimSpace(description)
-		projects[selected].Description = description
+	description, _ := ReadLine()
+	description = strings.TrimSpace(description)
+
+	fmt.Printf("Old Tags: %s\n", strings.Join(projects[selected].Tags, ", "))
+	fmt.Print("Tags (comma separated, - to remove all): ")
+	tag_list, _ := ReadLine()
+	tag_list = strings.TrimSpace(tag_list)
+
+	var tags []string
+	if tag_list == "-" {
+		tags = []string{}
+	} else if tag_list != "" {
+		tags = project.ParseTags(tag_list)
 	}
 
-	return projects
+	fields := readFieldUpdates(projects[selected])
+
+	return project.UpdateProject(store, projects[selected].ID, name, description, "", tags, fields)
 }
 
-func CreateNewProject(projects *[]project.Project) {
+func CreateNewProject(store project.ProjectStore) error {
+	projects, err := store.List()
+	if err != nil {
+		return err
+	}
+
 	header := "Create Project\n"
 
 	header += "Name: "
 	var name string
-	var err error
 	for {
 		name, err = readInputWithCancel(header, keyboard.KeyEsc)
 		if err != nil {
-			return
+			return nil
 		}
 		name = strings.TrimSpace(name)
-		if project.CheckDuplicateNames(projects, name) {
+		if project.CheckDuplicateNames(&projects, name) {
 
 			break
 		}
@@ -151,24 +403,44 @@ func CreateNewProject(projects *[]project.Project) {
 	header = "Create Project\nName: " + name + "\nDescription: "
 	description, err := readInputWithCancel(header, keyboard.KeyEsc)
 	if err != nil {
-		return
+		return nil
 	}
 	description = strings.TrimSpace(description)
-	header += description + "\n"
+	header += description + "\nTags (comma separated): "
+
+	tag_list, err := readInputWithCancel(header, keyboard.KeyEsc)
+	if err != nil {
+		return nil
+	}
+	tags := project.ParseTags(tag_list)
+	header += strings.Join(tags, ", ") + "\n"
+
+	fields, header, err := readFields(header)
+	if err != nil {
+		return nil
+	}
 
 	path, err := getExecutablePath()
 	if err != nil {
 		log.Fatal("Error while getting executable path", err)
 	}
 
-	path = PathChooser(header, path)
+	path = PathChooser(header, path, project.PinnedProjects(projects))
 
-	if path != "" {
-		project.AddProject(projects, name, description, path)
+	if path == "" {
+		return nil
 	}
+
+	_, err = project.AddProject(store, name, description, path, tags, fields)
+	return err
 }
 
-func LinkProject(projects *[]project.Project) {
+func LinkProject(store project.ProjectStore) error {
+	projects, err := store.List()
+	if err != nil {
+		return err
+	}
+
 	header := "Navigate to the project directory"
 
 	path, err := getExecutablePath()
@@ -177,19 +449,94 @@ func LinkProject(projects *[]project.Project) {
 		path = "~/"
 	}
 
-	path = PathChooser(header, path)
+	path = PathChooser(header, path, project.PinnedProjects(projects))
 
 	if path == "" {
-		return
+		return nil
 	}
 
-	name := strings.Split(path, "/")[len(strings.Split(path, "/"))-1]
+	name := filepath.Base(filepath.Clean(path))
 
-	header = fmt.Sprintf("Linking project\nName: %v", name)
-	description, err := readInputWithCancel(header)
+	header = fmt.Sprintf("Linking project\nName: %v\nDescription:", name)
+	description, err := readInputWithCancel(header, keyboard.KeyEsc)
 	if err != nil {
+		return nil
+	}
+	description = strings.TrimSpace(description)
+
+	header = fmt.Sprintf("Linking project\nName: %v\nDescription: %v\nTags (comma separated):", name, description)
+	tag_list, err := readInputWithCancel(header, keyboard.KeyEsc)
+	if err != nil {
+		return nil
+	}
+	tags := project.ParseTags(tag_list)
+
+	header = fmt.Sprintf("Linking project\nName: %v\nDescription: %v\nTags: %v\n", name, description, strings.Join(tags, ", "))
+	fields, _, err := readFields(header)
+	if err != nil {
+		return nil
+	}
+
+	_, err = project.LinkProject(store, name, description, path, tags, fields)
+	return err
+}
+
+// Shows message and waits for the user to acknowledge it
+func ShowMessage(message string) {
+	Clear()
+	fmt.Printf("%s\n\n", message)
+
+	waitForEnter()
+}
+
+// Shows err and waits for the user to acknowledge it
+func ShowError(err error) {
+	log.Println(err)
+
+	Clear()
+	fmt.Printf("Error: %v\n\n", err)
+
+	waitForEnter()
+}
+
+// Asks whether the projects salvaged from a corrupt registry should replace it
+func ConfirmRecovery(corrupt_err *project.CorruptRegistryError) bool {
+	Clear()
+
+	buffer := fmt.Sprintf("The projects file %s could not be read:\n  %v\n\n", corrupt_err.Path, corrupt_err.Err)
+	if corrupt_err.QuarantinePath != "" {
+		buffer += fmt.Sprintf("A copy of it was saved to %s\n\n", corrupt_err.QuarantinePath)
+	}
+	buffer += fmt.Sprintf("Recovered %d project(s):\n", len(corrupt_err.Recovered))
+	buffer += project.PrintCompressedProjectsSlice(corrupt_err.Recovered)
+	buffer += "\nContinue with the recovered projects and overwrite the file? (y/n)\n" +
+		"Answering no exits without touching the file."
+
+	fmt.Println(buffer)
+
+	char, _, err := readKey()
+	if err != nil {
+		log.Fatal("Error while getting keyboard key: ", err)
+	}
+
+	return char == 'y' || char == 'Y'
+}
+
+// Shows the projects another running instance edited at the same time
+func ShowConflicts(conflicts []project.MergeConflict) {
+	if len(conflicts) == 0 {
 		return
 	}
 
-	project.AddProject(projects, name, description, path)
+	Clear()
+
+	buffer := "The projects file was changed by another instance at the same time.\n" +
+		"Both sets of changes were merged, but these projects conflicted:\n\n"
+	for _, conflict := range conflicts {
+		buffer += "  " + conflict.String() + "\n"
+	}
+
+	fmt.Println(buffer)
+
+	waitForEnter()
 }
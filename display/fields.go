@@ -0,0 +1,155 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eiannone/keyboard"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+/*
+readFields asks for every custom field of the schema below header. Invalid
+values are asked for again, empty ones skip the field.
+
+Returns:
+- map[string]string: The values given, by field name.
+- string: header with the given values appended, to continue prompting below them.
+- error: An error if the input was cancelled.
+*/
+func readFields(header string) (map[string]string, string, error) {
+	fields := make(map[string]string)
+
+	for _, def := range project.FieldSchema() {
+		prompt := fmt.Sprintf("%s (%s): ", def.Name, def.Hint())
+		problem := ""
+
+		for {
+			value, err := readInputWithCancel(problem+header+prompt, keyboard.KeyEsc)
+			if err != nil {
+				return nil, header, err
+			}
+
+			if strings.TrimSpace(value) == "" {
+				break
+			}
+
+			checked, err := def.Check(value)
+			if err != nil {
+				problem = fmt.Sprintf("Invalid value, %v\n", err)
+				continue
+			}
+
+			fields[def.Name] = checked
+			header += fmt.Sprintf("%s: %s\n", def.Name, checked)
+			break
+		}
+	}
+
+	return fields, header, nil
+}
+
+// readFieldUpdates asks for new values of the custom fields of p line by
+// line. Empty answers keep the value and a dash removes it, invalid values
+// are asked for again.
+func readFieldUpdates(p project.Project) map[string]string {
+	fields := make(map[string]string)
+
+	for _, def := range project.FieldSchema() {
+		fmt.Printf("Old %s: %s\n", def.Name, p.Fields[def.Name])
+
+		for {
+			fmt.Printf("%s (%s, - to remove): ", def.Name, def.Hint())
+			value, _ := ReadLine()
+			value = strings.TrimSpace(value)
+
+			if value == "" {
+				break
+			}
+			if value == "-" {
+				fields[def.Name] = ""
+				break
+			}
+
+			checked, err := def.Check(value)
+			if err != nil {
+				fmt.Println("Invalid value,", err)
+				continue
+			}
+
+			fields[def.Name] = checked
+			break
+		}
+	}
+
+	return fields
+}
+
+// readFieldFilters asks for the custom field filters of the project list,
+// comma separated. An invalid filter keeps the current ones.
+func readFieldFilters(current []project.FieldFilter) []project.FieldFilter {
+	header := "Field filters, comma separated, like client=acme or due<2025-01-01 (empty for none):"
+
+	for {
+		input, err := readInputWithCancel(header, keyboard.KeyEsc)
+		if err != nil {
+			return current
+		}
+
+		var filters []project.FieldFilter
+		for _, filter := range strings.Split(input, ",") {
+			if strings.TrimSpace(filter) == "" {
+				continue
+			}
+
+			parsed, parse_err := project.ParseFieldFilter(filter)
+			if parse_err != nil {
+				err = parse_err
+				break
+			}
+			filters = append(filters, parsed)
+		}
+
+		if err == nil {
+			return filters
+		}
+
+		header = fmt.Sprintf("Invalid filter, %v\nField filters, comma separated (empty for none):", err)
+	}
+}
+
+// readSortKey lets the user pick what the project list is sorted by. An
+// empty key keeps pinned projects first in the order they were added.
+func readSortKey(current string) string {
+	keys := append([]string{"pinned first"}, project.SortKeys()...)
+
+	options := make([]string, 0, len(keys)*2-1)
+	options = append(options, keys[0])
+	for _, key := range keys[1:] {
+		options = append(options, key, key+" (descending)")
+	}
+
+	selected := ChoiceMenu(options, "Sort projects by:\n", "", "B", "b")
+	Clear()
+
+	switch {
+	case selected < 0 || selected >= len(options):
+		return current
+	case selected == 0:
+		return ""
+	case selected%2 == 0:
+		return "-" + keys[(selected+1)/2]
+	}
+
+	return keys[(selected+1)/2]
+}
+
+// describeFilters prints the field filters for a list header.
+func describeFilters(filters []project.FieldFilter) string {
+	described := make([]string, len(filters))
+	for i, filter := range filters {
+		described[i] = filter.Field + filter.Op + filter.Value
+	}
+
+	return strings.Join(described, ", ")
+}
@@ -0,0 +1,64 @@
+package display
+
+import (
+	"strings"
+	"unicode"
+)
+
+/*
+fuzzyScore matches query against text like fzf does: every rune of the
+query has to appear in text in the same order, ignoring case.
+
+Parameters:
+- query: What the user typed.
+- text: What it is matched against.
+
+Returns:
+- int: How good the match is. Runs of consecutive runes and matches at the start of words score higher, gaps lower.
+- bool: Whether it matched at all.
+*/
+func fuzzyScore(query, text string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+
+	query_runes := []rune(strings.ToLower(query))
+	text_runes := []rune(text)
+
+	score := 0
+	matched := 0
+	last := -1
+
+	for i := 0; i < len(text_runes) && matched < len(query_runes); i++ {
+		if unicode.ToLower(text_runes[i]) != query_runes[matched] {
+			continue
+		}
+
+		score += 1
+		if last >= 0 && i == last+1 {
+			score += 5
+		} else if last >= 0 {
+			score -= min(i-last-1, 3)
+		}
+		if i == 0 || isWordBoundary(text_runes[i-1], text_runes[i]) {
+			score += 8
+		}
+
+		last = i
+		matched++
+	}
+
+	return score, matched == len(query_runes)
+}
+
+func isWordBoundary(before, current rune) bool {
+	return !unicode.IsLetter(before) && !unicode.IsDigit(before) ||
+		unicode.IsLower(before) && unicode.IsUpper(current)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
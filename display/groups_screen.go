@@ -0,0 +1,179 @@
+package display
+
+import (
+	"fmt"
+
+	"github.com/eiannone/keyboard"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// (error) Lists the project groups to open or change them, and creates new
+// ones
+func GroupsScreen(store project.ProjectStore) error {
+	for {
+		groups, err := project.LoadGroups()
+		if err != nil {
+			return err
+		}
+
+		projects, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		options := make([]string, 0, len(groups)+2)
+		for _, g := range groups {
+			members, _ := project.GroupMembers(projects, g)
+			options = append(options, fmt.Sprintf("%s (%d project(s))", g.Name, len(members)))
+		}
+		options = append(options, "New Group", "Back")
+
+		selected := ChoiceMenu(options, "Groups:\n", "", "B", "b")
+		Clear()
+
+		switch {
+		case selected < 0 || selected == len(groups)+1:
+			return nil
+		case selected == len(groups):
+			err = createGroup(projects, groups)
+		default:
+			err = groupOptions(projects, groups[selected])
+		}
+
+		if err != nil {
+			ShowError(err)
+		}
+		Clear()
+	}
+}
+
+// (error) Asks for the name and the projects of a new group
+func createGroup(projects []project.Project, groups []project.Group) error {
+	header := "New Group\nName:"
+
+	var name string
+	for {
+		input, err := readInputWithCancel(header, keyboard.KeyEsc)
+		if err != nil {
+			return nil
+		}
+
+		if err := project.CheckGroupName(groups, input); err != nil {
+			header = fmt.Sprintf("Invalid name, %v\nNew Group\nName:", err)
+			continue
+		}
+
+		name = input
+		break
+	}
+
+	ids, ok := selectMembers(project.Group{Name: name}, projects)
+	if !ok {
+		return nil
+	}
+
+	_, err := project.CreateGroup(name, ids)
+	return err
+}
+
+// (error) Shows a group and what can be done with it
+func groupOptions(projects []project.Project, g project.Group) error {
+	header := project.PrintGroupInfo(g, projects) + "\nGroup Options\n"
+	options := []string{"Open Projects in Editor", "Open as VS Code Workspace", "Edit Projects", "Rename", "Delete", "Back"}
+
+	do_next := ChoiceMenu(options, header, "", "B", "b")
+	Clear()
+
+	switch do_next {
+	case 0:
+		return project.OpenGroup(projects, g, false)
+	case 1:
+		return project.OpenGroup(projects, g, true)
+	case 2:
+		ids, ok := selectMembers(g, projects)
+		if !ok {
+			return nil
+		}
+		return setMembers(g, projects, ids)
+	case 3:
+		new_name, err := readInputWithCancel(fmt.Sprintf("Rename group %s\nNew name:", g.Name), keyboard.KeyEsc)
+		if err != nil || new_name == "" {
+			return nil
+		}
+		return project.RenameGroup(g.Name, new_name)
+	case 4:
+		if confirm(project.PrintGroupInfo(g, projects) +
+			"\nAre you sure you want to delete this group? (y/n)\n" +
+			"\n**Its projects are kept**") {
+			return project.DeleteGroup(g.Name)
+		}
+	}
+
+	return nil
+}
+
+/*
+selectMembers lets the user pick the projects of a group among those that
+aren't archived. Enter toggles one, 'a' saves the picks.
+
+Returns:
+- []string: The IDs of the picked projects.
+- bool: false if the user cancelled with ESC or 'q'.
+*/
+func selectMembers(g project.Group, projects []project.Project) ([]string, bool) {
+	active := project.SortPinned(project.ActiveProjects(projects))
+
+	picked := make([]bool, len(active))
+	for i, p := range active {
+		picked[i] = g.HasMember(p.ID)
+	}
+
+	header := fmt.Sprintf("Pick the projects of %s  (Enter: toggle, a: save, ESC: cancel)\n\n", g.Name)
+	hotkeys := []Hotkey{{Char: 'a', Result: -3}, {Char: 'A', Result: -3}}
+	selected := 0
+
+	for {
+		options := make([]string, len(active))
+		for i, p := range active {
+			mark := "[ ]"
+			if picked[i] {
+				mark = "[x]"
+			}
+			options[i] = fmt.Sprintf("%s %s (%s)", mark, p.Name, p.Path)
+		}
+
+		selected = hotkeyMenuAt(options, header, "  No projects to pick.", hotkeys, selected, "Q", "q")
+		Clear()
+
+		switch {
+		case selected == -3:
+			var ids []string
+			for i, p := range active {
+				if picked[i] {
+					ids = append(ids, p.ID)
+				}
+			}
+			return ids, true
+		case selected < 0 || selected >= len(active):
+			return nil, false
+		}
+
+		picked[selected] = !picked[selected]
+	}
+}
+
+// (error) Makes ids the members of the group among the projects that
+// aren't archived, archived members stay
+func setMembers(g project.Group, projects []project.Project, ids []string) error {
+	picked := project.Group{Projects: ids}
+
+	var remove []string
+	for _, p := range project.ActiveProjects(projects) {
+		if g.HasMember(p.ID) && !picked.HasMember(p.ID) {
+			remove = append(remove, p.ID)
+		}
+	}
+
+	_, err := project.SetGroupMembers(g.Name, ids, remove)
+	return err
+}
@@ -0,0 +1,67 @@
+package display
+
+import (
+	"fmt"
+
+	import_export "github.com/yur4uwe/cmd-project-manager/import_export"
+)
+
+/*
+SelectImports lets the user pick which of the planned additions to apply.
+Every addition starts out picked. Enter toggles one, 'a' applies the picks.
+
+Parameters:
+- actions: The planned import, as returned by PlanImport or PlanSourceImport.
+
+Returns:
+- []bool: For every action whether it was picked. Only additions can be picked.
+- bool: false if the user cancelled with ESC or 'q'.
+*/
+func SelectImports(actions []import_export.ImportAction) ([]bool, bool) {
+	picked := make([]bool, len(actions))
+
+	var additions []int
+	for i, action := range actions {
+		if action.Action == import_export.ACTION_ADD {
+			picked[i] = true
+			additions = append(additions, i)
+		}
+	}
+
+	if len(additions) == 0 {
+		return picked, true
+	}
+
+	skipped := len(actions) - len(additions)
+	header := "Pick the projects to import  (Enter: toggle, a: import picked, ESC: cancel)\n"
+	if skipped > 0 {
+		header += fmt.Sprintf("%d already registered or conflicting project(s) are not listed.\n", skipped)
+	}
+	header += "\n"
+
+	hotkeys := []Hotkey{{Char: 'a', Result: -3}, {Char: 'A', Result: -3}}
+	selected := 0
+
+	for {
+		options := make([]string, len(additions))
+		for i, index := range additions {
+			mark := "[ ]"
+			if picked[index] {
+				mark = "[x]"
+			}
+			options[i] = fmt.Sprintf("%s %s (%s)", mark, actions[index].Project.Name, actions[index].Project.Path)
+		}
+
+		selected = hotkeyMenuAt(options, header, "", hotkeys, selected, "Q", "q")
+		Clear()
+
+		switch {
+		case selected == -3:
+			return picked, true
+		case selected < 0:
+			return nil, false
+		}
+
+		picked[additions[selected]] = !picked[additions[selected]]
+	}
+}
@@ -2,6 +2,7 @@ package display
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"strings"
 
@@ -20,41 +21,69 @@ Parameters:
 
 Returns:
 - int: The index of the selected option if the Enter key is pressed.
-- -1: If the ESC key is pressed.
+- -1: If the ESC key is pressed, or Enter while there are no options.
 - -2: If a key from the termination_options slice is pressed.
 */
 func ChoiceMenu(options []string, header string, no_options string, termination_options ...string) int {
-	selected := 0
+	return HotkeyMenu(options, header, no_options, nil, termination_options...)
+}
 
-	for {
-		display_string := header
+// Hotkey makes HotkeyMenu return Result when its character or key is pressed.
+// In line mode it is typed as Line, or as Char if Line is empty.
+type Hotkey struct {
+	Char   rune
+	Key    keyboard.Key
+	Line   string
+	Result int
+}
 
-		for i, option := range options {
-			if i == selected {
-				display_string += fmt.Sprintf("> %s <\n", option)
-			} else {
-				display_string += fmt.Sprintf("  %s\n", option)
-			}
-		}
+/*
+HotkeyMenu is ChoiceMenu with extra keys that return a value of their own.
 
-		if len(options) == 0 {
-			display_string = header + no_options
-		}
+Parameters:
+- options, header, no_options, termination_options: Same as for ChoiceMenu.
+- hotkeys: Keys checked before anything else. A Hotkey matches on Char if it is set, otherwise on Key.
+
+Returns:
+- int: Same as ChoiceMenu, or the Result of the pressed hotkey.
+*/
+func HotkeyMenu(options []string, header string, no_options string, hotkeys []Hotkey, termination_options ...string) int {
+	return hotkeyMenuAt(options, header, no_options, hotkeys, 0, termination_options...)
+}
 
-		fmt.Println(display_string)
+// hotkeyMenuAt is HotkeyMenu with the cursor starting on option selected.
+func hotkeyMenuAt(options []string, header string, no_options string, hotkeys []Hotkey, selected int, termination_options ...string) int {
+	if line_mode {
+		return lineMenu(options, header, no_options, hotkeys, termination_options...)
+	}
+
+	for {
+		fmt.Fprintln(output, renderMenu(options, selected, header, no_options))
 
 		char, key, err := keyboard.GetKey()
 		if err != nil {
 			log.Fatal("Error while getting keyboard key: ", err)
 		}
 
+		if result, ok := matchHotkey(hotkeys, char, key); ok {
+			return result
+		}
+
+		// An empty menu only shows no_options, Enter leaves it like ESC
 		if key == keyboard.KeyArrowDown {
-			selected = (selected + 1) % len(options)
+			if len(options) > 0 {
+				selected = (selected + 1) % len(options)
+			}
 			Clear()
 		} else if key == keyboard.KeyArrowUp {
-			selected = (selected - 1 + len(options)) % len(options)
+			if len(options) > 0 {
+				selected = (selected - 1 + len(options)) % len(options)
+			}
 			Clear()
 		} else if key == keyboard.KeyEnter {
+			if len(options) == 0 {
+				return -1
+			}
 			return selected
 		} else if key == keyboard.KeyEsc {
 			return -1
@@ -63,21 +92,40 @@ func ChoiceMenu(options []string, header string, no_options string, termination_
 		} else {
 			Clear()
 		}
+	}
+}
 
-		for i, option := range options {
-			if i == selected {
-				display_string += fmt.Sprintf("> %s <\n", option)
-			} else {
-				display_string += fmt.Sprintf("  %s\n", option)
-			}
-		}
+// renderMenu draws the options under header with the selected one marked,
+// or no_options if there are none.
+func renderMenu(options []string, selected int, header string, no_options string) string {
+	if len(options) == 0 {
+		return header + no_options
+	}
+
+	display_string := header
 
-		if len(options) == 0 {
-			display_string = header + no_options
+	for i, option := range options {
+		if i == selected {
+			display_string += fmt.Sprintf("> %s <\n", option)
+		} else {
+			display_string += fmt.Sprintf("  %s\n", option)
 		}
+	}
+
+	return display_string
+}
 
-		fmt.Println(display_string)
+func matchHotkey(hotkeys []Hotkey, char rune, key keyboard.Key) (int, bool) {
+	for _, hotkey := range hotkeys {
+		if hotkey.Char != 0 && hotkey.Char == char {
+			return hotkey.Result, true
+		}
+		if hotkey.Char == 0 && hotkey.Key == key {
+			return hotkey.Result, true
+		}
 	}
+
+	return 0, false
 }
 
 /*
@@ -95,11 +143,22 @@ Possible Returns:
 - If the input is canceled by pressing one of the escape keys, the function returns an empty string and an error with the message "input cancelled".
 - If the Enter key is pressed and the input is valid according to the predicate, the function returns the input string and nil error.
 - If there is an error while getting the keyboard input, the function returns an empty string and the error.
+- In line mode the input is the next line, and the end of the input cancels.
 */
 func readInputWithCancel(header string, escape_keys ...keyboard.Key) (string, error) {
 	defer Clear()
 	input := ""
 
+	if line_mode {
+		fmt.Fprint(output, header, " ")
+		input, err := ReadLine()
+		if err == io.EOF {
+			fmt.Fprintln(output)
+			return "", fmt.Errorf("input cancelled")
+		}
+		return strings.TrimSpace(input), err
+	}
+
 	for {
 		fmt.Println(header, input)
 		char, key, err := keyboard.GetKey()
@@ -133,13 +192,18 @@ PathChooser allows the user to choose a path by navigating through the filesyste
 Parameters:
 - header: A string to display as the header for the path chooser.
 - current_path: The current path to start from.
+- pinned: The pinned projects, whose paths are suggested before the recent ones.
 
 Returns:
 - string: The chosen path if the Enter key is pressed and the path is valid.
 - If the ESC key is pressed, the function returns an empty string.
 */
-func PathChooser(header string, current_path string) string {
-	var recent_path_options = GetMostRecentPaths()
+func PathChooser(header string, current_path string, pinned []project.Project) string {
+	if line_mode {
+		return linePathChooser(header, current_path, pinned)
+	}
+
+	var recent_path_options = suggestedPaths(pinned)
 
 	header += "\nEnter the absolute path to the project directory or choose already existing."
 
@@ -217,12 +281,12 @@ func PathChooser(header string, current_path string) string {
 AddProjectInterface provides an interface for adding a new project or linking an existing project.
 
 Parameters:
-- projects: A pointer to a slice of Project structs.
+- store: The project store the new project is saved to.
 
 Returns:
-- void: This function mutates the projects slice.
+- error: An error if the project could not be saved.
 */
-func AddProjectInterface(projects *[]project.Project) {
+func AddProjectInterface(store project.ProjectStore) error {
 	add_options := []string{
 		"Create new Project", "Link an already created project",
 	}
@@ -230,11 +294,11 @@ func AddProjectInterface(projects *[]project.Project) {
 	option := ChoiceMenu(add_options, "", "")
 
 	switch option {
-	case -1:
-		return
 	case 0:
-		CreateNewProject(projects)
+		return CreateNewProject(store)
 	case 1:
-		LinkProject(projects)
+		return LinkProject(store)
 	}
+
+	return nil
 }
@@ -0,0 +1,243 @@
+package display
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eiannone/keyboard"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// line_mode is set when there is no terminal to read single keys from.
+// Menus are then numbered and every answer is a line read from stdin.
+var line_mode = false
+
+var line_reader = bufio.NewReader(os.Stdin)
+
+// UseLineMode switches every menu and prompt to line mode.
+func UseLineMode() {
+	line_mode = true
+}
+
+/*
+OpenKeyboard puts the terminal in raw mode for the menus. When stdin is not
+a terminal, or raw mode is not available, it switches to line mode instead.
+
+Returns:
+- func(): Restores the terminal, a no-op in line mode.
+*/
+func OpenKeyboard() func() {
+	if line_mode || !stdinIsTerminal() {
+		UseLineMode()
+		return func() {}
+	}
+
+	if err := keyboard.Open(); err != nil {
+		log.Println("Error while opening the keyboard, using line mode: ", err)
+		UseLineMode()
+		return func() {}
+	}
+
+	return func() { keyboard.Close() }
+}
+
+// stdinIsTerminal reports whether stdin is a terminal rather than a pipe or file.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// ReadLine reads a line from stdin without its line ending. Returns io.EOF
+// only once nothing at all is left. Every prompt reads through it, so none
+// of them buffers away input meant for the next.
+func ReadLine() (string, error) {
+	line, err := line_reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// readKey is keyboard.GetKey that also works in line mode, where the first
+// character of a line stands for the key. An empty line is Enter and the
+// end of the input is ESC.
+func readKey() (rune, keyboard.Key, error) {
+	if !line_mode {
+		return keyboard.GetKey()
+	}
+
+	line, err := ReadLine()
+	if err == io.EOF {
+		return 0, keyboard.KeyEsc, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return 0, keyboard.KeyEnter, nil
+	}
+
+	return []rune(line)[0], 0, nil
+}
+
+/*
+lineMenu is HotkeyMenu in line mode. The options are numbered from 1 and
+the user types a number, a hotkey or a termination option.
+
+Returns:
+- int: Same as HotkeyMenu. An empty line is ESC, the end of the input is a termination option so loops over menus end.
+*/
+func lineMenu(options []string, header string, no_options string, hotkeys []Hotkey, termination_options ...string) int {
+	fmt.Fprint(output, header)
+	if len(options) == 0 {
+		fmt.Fprintln(output, no_options)
+	}
+	for i, option := range options {
+		fmt.Fprintf(output, "  %d) %s\n", i+1, option)
+	}
+
+	choices := []string{}
+	if len(options) == 1 {
+		choices = append(choices, "1")
+	} else if len(options) > 1 {
+		choices = append(choices, fmt.Sprintf("1-%d", len(options)))
+	}
+	for _, hotkey := range hotkeys {
+		choices = append(choices, hotkey.lineWord())
+	}
+	choices = append(choices, termination_options...)
+
+	for {
+		fmt.Fprintf(output, "Choice (%s, empty to go back): ", strings.Join(choices, ", "))
+
+		line, err := ReadLine()
+		if err != nil {
+			fmt.Fprintln(output)
+			return -2
+		}
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			return -1
+		}
+
+		if number, err := strconv.Atoi(line); err == nil && number >= 1 && number <= len(options) {
+			return number - 1
+		}
+
+		for _, hotkey := range hotkeys {
+			if line == hotkey.lineWord() {
+				return hotkey.Result
+			}
+		}
+
+		for _, option := range termination_options {
+			if line == option {
+				return -2
+			}
+		}
+
+		fmt.Fprintln(output, "Invalid choice.")
+	}
+}
+
+// label is how to show the hotkey in a header, which differs in line mode.
+func (h Hotkey) label() string {
+	if line_mode {
+		return h.lineWord()
+	}
+
+	return string(h.Char)
+}
+
+// lineWord is what to type for the hotkey in line mode.
+func (h Hotkey) lineWord() string {
+	if h.Line != "" {
+		return h.Line
+	}
+
+	return string(h.Char)
+}
+
+// linePathChooser is PathChooser in line mode.
+func linePathChooser(header string, current_path string, pinned []project.Project) string {
+	recent_paths := suggestedPaths(pinned)
+
+	fmt.Fprintln(output, header)
+	for i, recent_path := range recent_paths {
+		fmt.Fprintf(output, "  %d) %s\n", i+1, recent_path)
+	}
+
+	for {
+		fmt.Fprintf(output, "Path (a number for a recent one, empty for %s): ", current_path)
+
+		line, err := ReadLine()
+		if err != nil {
+			fmt.Fprintln(output)
+			return ""
+		}
+		path := strings.TrimSpace(line)
+
+		if number, err := strconv.Atoi(path); err == nil && number >= 1 && number <= len(recent_paths) {
+			path = recent_paths[number-1]
+		} else if path == "" {
+			path = current_path
+		}
+
+		if isValidPath(path) {
+			return path
+		}
+
+		fmt.Fprintln(output, "Invalid path. Please enter a valid filesystem path.")
+	}
+}
+
+// linePicker is FuzzyPicker in line mode. A number picks from the matches
+// listed last, anything else is a new query.
+func linePicker(projects []project.Project, frecencies []float64, header string) int {
+	query := ""
+
+	for {
+		matches := rankProjects(projects, frecencies, query)
+		if len(matches) > PICKER_ROWS {
+			matches = matches[:PICKER_ROWS]
+		}
+
+		fmt.Fprintln(output, header)
+		if len(matches) == 0 {
+			fmt.Fprintln(output, "  No matching projects.")
+		}
+		for i, match := range matches {
+			fmt.Fprintf(output, "  %d) %s\n", i+1, pickLabel(projects[match.index]))
+		}
+		fmt.Fprint(output, "Number to pick, text to filter, empty to cancel: ")
+
+		line, err := ReadLine()
+		if err != nil {
+			fmt.Fprintln(output)
+			return -1
+		}
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			return -1
+		}
+
+		if number, err := strconv.Atoi(line); err == nil && number >= 1 && number <= len(matches) {
+			return matches[number-1].index
+		}
+
+		query = line
+	}
+}
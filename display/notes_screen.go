@@ -0,0 +1,118 @@
+package display
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/eiannone/keyboard"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+/*
+NotesScreen shows the notes and the checklist of a project. Picking an
+item toggles it, the options below the checklist add and remove items and
+edit the notes.
+
+Parameters:
+- store: The project store, every change is saved right away.
+- id: The ID of the project.
+
+Returns:
+- error: An error if the project could not be read or saved.
+*/
+func NotesScreen(store project.ProjectStore, id string) error {
+	selected := 0
+
+	for {
+		p, err := store.Get(id)
+		if err != nil {
+			return err
+		}
+
+		header := fmt.Sprintf("Notes of %s\n\n%s\n", p.Name, strings.TrimRight(p.Notes, "\n"))
+		if strings.TrimSpace(p.Notes) == "" {
+			header = fmt.Sprintf("Notes of %s\n\nNo notes yet.\n", p.Name)
+		}
+		header += fmt.Sprintf("\nChecklist, %d open  (Enter on an item: toggle)\n", project.OpenChecklistItems(p))
+
+		items := len(p.Checklist)
+		options := make([]string, 0, items+4)
+		for _, item := range p.Checklist {
+			options = append(options, item.String())
+		}
+		options = append(options, "Add Item", "Remove Item", "Edit Notes", "Back")
+
+		selected = hotkeyMenuAt(options, header, "", nil, selected, "B", "b")
+		Clear()
+
+		switch {
+		case selected < 0 || selected == items+3:
+			return nil
+		case selected < items:
+			err = project.ToggleChecklistItem(&p, selected+1)
+		case selected == items:
+			text, input_err := readInputWithCancel("New checklist item:", keyboard.KeyEsc)
+			if input_err != nil || strings.TrimSpace(text) == "" {
+				continue
+			}
+			err = project.AddChecklistItem(&p, text)
+		case selected == items+1:
+			item_options := options[:items]
+			remove := ChoiceMenu(item_options, "Remove which item?\n", "  The checklist is empty.")
+			Clear()
+			if remove < 0 || remove >= items {
+				continue
+			}
+			err = project.RemoveChecklistItem(&p, remove+1)
+		case selected == items+2:
+			p.Notes, err = editNotes(p.Notes)
+			Clear()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := store.Put(p); err != nil {
+			return err
+		}
+	}
+}
+
+// editNotes lets the user rewrite notes in their editor, or line by line
+// in line mode.
+func editNotes(notes string) (string, error) {
+	if line_mode {
+		fmt.Fprintf(output, "Current notes:\n%s\n", notes)
+		fmt.Fprintln(output, "Type the new notes and end them with a line with only a dot.")
+		fmt.Fprintln(output, "Only a dot keeps the current notes, a dash alone removes them.")
+
+		var lines []string
+		for {
+			line, err := ReadLine()
+			if err != nil || line == "." {
+				break
+			}
+			lines = append(lines, line)
+		}
+
+		switch {
+		case len(lines) == 0:
+			return notes, nil
+		case len(lines) == 1 && strings.TrimSpace(lines[0]) == "-":
+			return "", nil
+		}
+
+		return strings.Join(lines, "\n") + "\n", nil
+	}
+
+	// The editor needs the terminal back in its normal mode
+	keyboard.Close()
+	edited, err := project.EditNotes(notes)
+	if open_err := keyboard.Open(); open_err != nil {
+		log.Fatal("Error while opening the keyboard: ", open_err)
+	}
+
+	return edited, err
+}
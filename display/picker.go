@@ -0,0 +1,174 @@
+package display
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/eiannone/keyboard"
+	path_manager "github.com/yur4uwe/cmd-project-manager/manage_paths"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// PICKER_ROWS is how many matches the picker shows at once.
+const PICKER_ROWS = 15
+
+// pickCandidate is a project with how well it matches the current query.
+type pickCandidate struct {
+	index    int
+	score    int
+	frecency float64
+}
+
+/*
+FuzzyPicker lets the user filter the projects by typing and pick one.
+Names, descriptions and paths are matched fuzzily, names weigh the most.
+Projects opened often and recently rank higher, and without a query they
+are sorted by that alone.
+
+Parameters:
+- projects: The projects to pick from.
+- header: A line to show above the query.
+
+Returns:
+- int: The index in projects of the picked project, or -1 if the user cancelled with ESC or Ctrl+C.
+
+In line mode the user types a query to filter by, then the number of a match.
+*/
+func FuzzyPicker(projects []project.Project, header string) int {
+	frecencies := projectFrecencies(projects)
+
+	if line_mode {
+		return linePicker(projects, frecencies, header)
+	}
+
+	query := ""
+	selected := 0
+
+	for {
+		matches := rankProjects(projects, frecencies, query)
+		if selected >= len(matches) {
+			selected = len(matches) - 1
+		}
+		if selected < 0 {
+			selected = 0
+		}
+
+		shown := matches
+		if len(shown) > PICKER_ROWS {
+			shown = shown[:PICKER_ROWS]
+		}
+
+		options := make([]string, len(shown))
+		for i, match := range shown {
+			options[i] = pickLabel(projects[match.index])
+		}
+
+		Clear()
+		prompt := fmt.Sprintf("%s  (%d/%d)\n> %s\n\n", header, len(matches), len(projects), query)
+		fmt.Fprintln(output, renderMenu(options, selected, prompt, "  No matching projects."))
+
+		char, key, err := keyboard.GetKey()
+		if err != nil {
+			log.Fatal("Error while getting keyboard key: ", err)
+		}
+
+		switch {
+		case key == keyboard.KeyEnter:
+			if len(shown) == 0 {
+				continue
+			}
+			Clear()
+			return shown[selected].index
+		case key == keyboard.KeyEsc || key == keyboard.KeyCtrlC:
+			Clear()
+			return -1
+		case key == keyboard.KeyArrowDown || key == keyboard.KeyCtrlN:
+			if len(shown) > 0 {
+				selected = (selected + 1) % len(shown)
+			}
+		case key == keyboard.KeyArrowUp || key == keyboard.KeyCtrlP:
+			if len(shown) > 0 {
+				selected = (selected - 1 + len(shown)) % len(shown)
+			}
+		case key == keyboard.KeyBackspace || key == keyboard.KeyBackspace2:
+			if len(query) > 0 {
+				runes := []rune(query)
+				query = string(runes[:len(runes)-1])
+				selected = 0
+			}
+		case key == keyboard.KeySpace:
+			query += " "
+			selected = 0
+		case char != 0:
+			query += string(char)
+			selected = 0
+		}
+	}
+}
+
+func pickLabel(p project.Project) string {
+	if p.Description == "" {
+		return fmt.Sprintf("%s (%s)", p.Name, p.Path)
+	}
+
+	return fmt.Sprintf("%s - %s (%s)", p.Name, p.Description, p.Path)
+}
+
+// projectFrecencies looks up the frecency of every project in the path history.
+func projectFrecencies(projects []project.Project) []float64 {
+	history, err := path_manager.ReadRecentPathsFromFile()
+	if err != nil {
+		log.Println("Error while reading path history for the picker: ", err)
+	}
+
+	now := time.Now()
+	by_path := make(map[string]float64, len(history))
+	for _, recent_path := range history {
+		by_path[recent_path.Path] = path_manager.Frecency(recent_path, now)
+	}
+
+	frecencies := make([]float64, len(projects))
+	for i, p := range projects {
+		frecencies[i] = by_path[p.Path]
+	}
+
+	return frecencies
+}
+
+// rankProjects returns the projects matching query, best first.
+func rankProjects(projects []project.Project, frecencies []float64, query string) []pickCandidate {
+	var matches []pickCandidate
+
+	for i, p := range projects {
+		best, found := 0, false
+
+		if score, ok := fuzzyScore(query, p.Name); ok {
+			best, found = score*2, true
+		}
+		for _, text := range []string{p.Description, p.Path} {
+			if score, ok := fuzzyScore(query, text); ok && (!found || score > best) {
+				best, found = score, true
+			}
+		}
+
+		if found {
+			matches = append(matches, pickCandidate{index: i, score: best, frecency: frecencies[i]})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		// Frecency breaks ties between similar matches without outweighing
+		// a clearly better one.
+		score_i := matches[i].score + min(int(matches[i].frecency), 10)
+		score_j := matches[j].score + min(int(matches[j].frecency), 10)
+		if score_i != score_j {
+			return score_i > score_j
+		}
+
+		return matches[i].frecency > matches[j].frecency
+	})
+
+	return matches
+}
@@ -0,0 +1,125 @@
+package display
+
+import (
+	"fmt"
+
+	"github.com/eiannone/keyboard"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+	snapshot "github.com/yur4uwe/cmd-project-manager/snapshots"
+)
+
+// Lists the snapshots and lets the user take, inspect and restore them
+func SnapshotsScreen(store project.ProjectStore, manager *snapshot.Manager) error {
+	for {
+		snapshots, err := manager.List()
+		if err != nil {
+			return err
+		}
+
+		options := []string{"Take a snapshot now"}
+		for _, snap := range snapshots {
+			options = append(options, snap.String())
+		}
+
+		selected := ChoiceMenu(options, "Snapshots\n", "", "B", "b")
+		Clear()
+
+		if selected < 0 {
+			return nil
+		}
+
+		if selected == 0 {
+			label, err := readInputWithCancel("Snapshot label:", keyboard.KeyEsc)
+			if err != nil {
+				continue
+			}
+			if _, err := manager.Capture(label, "taken by hand", false, store); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := snapshotOptions(store, manager, snapshots[selected-1]); err != nil {
+			return err
+		}
+	}
+}
+
+func snapshotOptions(store project.ProjectStore, manager *snapshot.Manager, snap snapshot.Snapshot) error {
+	saved, _, err := manager.Read(snap)
+	if err != nil {
+		return err
+	}
+
+	current, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	changes := project.DiffProjects(saved, current)
+
+	header := fmt.Sprintf("Snapshot %s\n%d project(s), %d change(s) since it was taken\n\n", snap, len(saved), len(changes))
+	options := []string{"Show changes since this snapshot", "Restore everything", "Restore a single project", "Back"}
+
+	switch ChoiceMenu(options, header, "", "B", "b") {
+	case 0:
+		ShowMessage(describeChanges(changes))
+	case 1:
+		Clear()
+		if !confirm(header + "Replace all projects and the path history with this snapshot? (y/n)") {
+			return nil
+		}
+		if err := manager.Restore(snap, store); err != nil {
+			return err
+		}
+		ShowMessage("Restored snapshot " + snap.Label + ".\nThe previous state was saved as a snapshot too.")
+	case 2:
+		return restoreSingleProject(store, changes)
+	}
+
+	return nil
+}
+
+// restoreSingleProject puts back one project the way the snapshot has it
+func restoreSingleProject(store project.ProjectStore, changes []project.ProjectChange) error {
+	var restorable []project.ProjectChange
+	var options []string
+
+	for _, change := range changes {
+		switch change.Change {
+		case project.CHANGE_REMOVED:
+			restorable = append(restorable, change)
+			options = append(options, fmt.Sprintf("%s (removed since)", change.Old.Name))
+		case project.CHANGE_CHANGED:
+			restorable = append(restorable, change)
+			options = append(options, fmt.Sprintf("%s (changed since)", change.Old.Name))
+		}
+	}
+
+	Clear()
+	selected := ChoiceMenu(options, "Choose the project to restore:\n", "  Every project is the same as in the snapshot.")
+	if selected < 0 || selected >= len(restorable) {
+		return nil
+	}
+
+	if err := store.Put(*restorable[selected].Old); err != nil {
+		return err
+	}
+
+	ShowMessage("Restored project " + restorable[selected].Old.Name + ".")
+
+	return nil
+}
+
+func describeChanges(changes []project.ProjectChange) string {
+	if len(changes) == 0 {
+		return "Nothing changed since this snapshot."
+	}
+
+	description := "Changes from the snapshot to now:\n"
+	for _, change := range changes {
+		description += "  " + change.String() + "\n"
+	}
+
+	return description
+}
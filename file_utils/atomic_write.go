@@ -0,0 +1,154 @@
+package file_utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// BackupSuffix is appended to a file name to get its previous generation.
+const BackupSuffix = ".bak"
+
+// The file operations that can be interrupted, replaced by the tests.
+var (
+	syncFile   = (*os.File).Sync
+	renameFile = os.Rename
+)
+
+/*
+WriteFileAtomic replaces the file at path with data without ever leaving a
+partially written file behind.
+
+The data is written to a temporary file in the same directory, flushed to
+disk and renamed over the original. Before the rename the current contents
+are kept as path + BackupSuffix, so the last good generation survives even
+if the new one turns out to be wrong.
+
+Parameters:
+- path: The file to replace.
+- data: The new contents.
+- perm: The permissions of the file if it has to be created.
+
+Returns:
+- error: An error if any step failed. The original file and its backup are untouched in that case.
+*/
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	tmp_path, err := writeTempFile(dir, filepath.Base(path), data, perm)
+	if err != nil {
+		return err
+	}
+
+	restore_backup, err := backupFile(path)
+	if err != nil {
+		os.Remove(tmp_path)
+		return err
+	}
+
+	if err := renameFile(tmp_path, path); err != nil {
+		os.Remove(tmp_path)
+		restore_backup()
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+
+	syncDir(dir)
+
+	return nil
+}
+
+// writeTempFile writes data to a new fsynced file in dir and returns its path.
+func writeTempFile(dir, name string, data []byte, perm os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp file for %s: %w", name, err)
+	}
+	tmp_path := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = syncFile(tmp)
+	}
+	if close_err := tmp.Close(); err == nil {
+		err = close_err
+	}
+	if err == nil {
+		err = os.Chmod(tmp_path, perm)
+	}
+
+	if err != nil {
+		os.Remove(tmp_path)
+		return "", fmt.Errorf("write temp file for %s: %w", name, err)
+	}
+
+	return tmp_path, nil
+}
+
+// backupFile atomically copies the current contents of path to its backup.
+// A missing file has nothing to back up. The returned function puts the
+// previous backup back, for when the new generation can't be written.
+func backupFile(path string) (func(), error) {
+	current, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return func() {}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("open %s for backup: %w", path, err)
+	}
+	defer current.Close()
+
+	data, err := io.ReadAll(current)
+	if err != nil {
+		return nil, fmt.Errorf("read %s for backup: %w", path, err)
+	}
+
+	info, err := current.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat %s for backup: %w", path, err)
+	}
+
+	backup_path := path + BackupSuffix
+	previous, previous_err := os.ReadFile(backup_path)
+
+	dir := filepath.Dir(path)
+	tmp_path, err := writeTempFile(dir, filepath.Base(backup_path), data, info.Mode().Perm())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := renameFile(tmp_path, backup_path); err != nil {
+		os.Remove(tmp_path)
+		return nil, fmt.Errorf("replace backup of %s: %w", path, err)
+	}
+
+	restore := func() {
+		if os.IsNotExist(previous_err) {
+			os.Remove(backup_path)
+			return
+		}
+		if previous_err != nil {
+			return
+		}
+
+		tmp_path, err := writeTempFile(dir, filepath.Base(backup_path), previous, info.Mode().Perm())
+		if err != nil {
+			return
+		}
+		if err := renameFile(tmp_path, backup_path); err != nil {
+			os.Remove(tmp_path)
+		}
+	}
+
+	return restore, nil
+}
+
+// syncDir flushes the directory entry so the rename itself is durable.
+// Not every platform can open a directory for syncing, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,115 @@
+package file_utils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeGenerations sets up a file with a current and a backup generation.
+func writeGenerations(t *testing.T) (string, []byte, []byte) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "projects.json")
+	current := []byte(`[{"Name":"current"}]`)
+	backup := []byte(`[{"Name":"backup"}]`)
+
+	if err := os.WriteFile(path, current, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+BackupSuffix, backup, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, current, backup
+}
+
+// assertIntact fails unless the file and its backup hold their old
+// contents and no temp file is left in the directory.
+func assertIntact(t *testing.T, path string, current, backup []byte) {
+	t.Helper()
+
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, current) {
+		t.Errorf("file is %q, %v, want %q", data, err, current)
+	}
+	if data, err := os.ReadFile(path + BackupSuffix); err != nil || !bytes.Equal(data, backup) {
+		t.Errorf("backup is %q, %v, want %q", data, err, backup)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("temp file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path, current, _ := writeGenerations(t)
+	next := []byte(`[{"Name":"next"}]`)
+
+	if err := WriteFileAtomic(path, next, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The old file becomes the backup, and nothing else is left
+	assertIntact(t, path, next, current)
+}
+
+func TestWriteFileAtomicTempWriteFails(t *testing.T) {
+	path, current, backup := writeGenerations(t)
+
+	syncFile = func(*os.File) error { return errors.New("disk full") }
+	defer func() { syncFile = (*os.File).Sync }()
+
+	if err := WriteFileAtomic(path, []byte(`[{"Name":"next"}]`), 0644); err == nil {
+		t.Fatal("write succeeded, want the sync error")
+	}
+
+	assertIntact(t, path, current, backup)
+}
+
+func TestWriteFileAtomicRenameFails(t *testing.T) {
+	path, current, backup := writeGenerations(t)
+
+	// Only the rename over the file itself fails, after the backup was taken
+	renameFile = func(old_path, new_path string) error {
+		if new_path == path {
+			return errors.New("interrupted")
+		}
+		return os.Rename(old_path, new_path)
+	}
+	defer func() { renameFile = os.Rename }()
+
+	if err := WriteFileAtomic(path, []byte(`[{"Name":"next"}]`), 0644); err == nil {
+		t.Fatal("write succeeded, want the rename error")
+	}
+
+	assertIntact(t, path, current, backup)
+}
+
+func TestWriteFileAtomicUnwritableDir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+
+	path, current, backup := writeGenerations(t)
+	dir := filepath.Dir(path)
+
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0755)
+
+	if err := WriteFileAtomic(path, []byte(`[{"Name":"next"}]`), 0644); err == nil {
+		t.Fatal("write succeeded in a read-only directory")
+	}
+
+	assertIntact(t, path, current, backup)
+}
@@ -0,0 +1,36 @@
+package file_utils
+
+import (
+	"fmt"
+	"os"
+)
+
+// FileLock is an advisory lock held on path + ".lock". It only keeps out
+// other processes that take the same lock, not plain readers and writers.
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile blocks until the lock for path is acquired.
+func LockFile(path string) (*FileLock, error) {
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file for %s: %w", path, err)
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+
+	return &FileLock{file: file}, nil
+}
+
+func (l *FileLock) Unlock() error {
+	err := unlockFile(l.file)
+	if close_err := l.file.Close(); err == nil {
+		err = close_err
+	}
+
+	return err
+}
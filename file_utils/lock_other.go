@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package file_utils
+
+import "os"
+
+// Platforms without advisory locks run unlocked.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,204 @@
+package file_utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+CompressDir packs the directory dir into a gzipped tar file at path. Regular
+files, directories and symbolic links are kept with their permissions and
+modification times, anything else is skipped.
+
+The archive is written to a temporary file next to path and only renamed
+into place once it is complete.
+
+Parameters:
+- dir: The directory to pack. Entries in the archive are relative to it.
+- path: The archive to create. An existing file is replaced.
+
+Returns:
+- error: An error if the directory can't be read or the archive can't be written. No archive is left behind in that case.
+*/
+func CompressDir(dir, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create archive for %s: %w", dir, err)
+	}
+	tmp_path := tmp.Name()
+
+	err = writeTarGz(tmp, dir)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if close_err := tmp.Close(); err == nil {
+		err = close_err
+	}
+
+	if err != nil {
+		os.Remove(tmp_path)
+		return fmt.Errorf("write archive of %s: %w", dir, err)
+	}
+
+	if err := os.Rename(tmp_path, path); err != nil {
+		os.Remove(tmp_path)
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+
+	syncDir(filepath.Dir(path))
+
+	return nil
+}
+
+func writeTarGz(w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, file)
+		if err != nil || name == "." {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+/*
+ExtractArchive unpacks a gzipped tar file made by CompressDir into dir,
+creating it if needed.
+
+Parameters:
+- path: The archive to unpack.
+- dir: Where to unpack it. Entries that would end up outside of it are refused.
+
+Returns:
+- error: An error if the archive can't be read or an entry can't be written.
+*/
+func ExtractArchive(path, dir string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("read archive %s: %w", path, err)
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create %s: %w", dir, err)
+	}
+
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("read archive %s: %w", path, err)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if target != filepath.Clean(dir) && !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %q points outside of %s", header.Name, dir)
+		}
+
+		if err := extractEntry(tr, header, target); err != nil {
+			return fmt.Errorf("extract %s: %w", header.Name, err)
+		}
+	}
+}
+
+func extractEntry(r io.Reader, header *tar.Header, target string) error {
+	mode := os.FileMode(header.Mode).Perm()
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		// Keep it writable, or the entries that follow couldn't be created in it
+		return os.Chmod(target, mode|0700)
+	case tar.TypeSymlink:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.Symlink(header.Linkname, target)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(f, r)
+		if close_err := f.Close(); err == nil {
+			err = close_err
+		}
+		if err != nil {
+			return err
+		}
+
+		return os.Chtimes(target, header.ModTime, header.ModTime)
+	}
+
+	return nil
+}
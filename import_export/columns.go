@@ -0,0 +1,217 @@
+package import_export
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// column is a Project field as it appears in exported and imported files.
+// Every value is written as a string so all formats share one layout.
+// Columns without set are only exported.
+type column struct {
+	name string
+	get  func(p project.Project) string
+	set  func(p *project.Project, value string) error
+}
+
+var columns = []column{
+	{
+		name: "ID",
+		get:  func(p project.Project) string { return p.ID },
+		set:  func(p *project.Project, value string) error { p.ID = value; return nil },
+	},
+	{
+		name: "Name",
+		get:  func(p project.Project) string { return p.Name },
+		set:  func(p *project.Project, value string) error { p.Name = value; return nil },
+	},
+	{
+		name: "Description",
+		get:  func(p project.Project) string { return p.Description },
+		set:  func(p *project.Project, value string) error { p.Description = value; return nil },
+	},
+	{
+		name: "Path",
+		get:  func(p project.Project) string { return p.Path },
+		set:  func(p *project.Project, value string) error { p.Path = value; return nil },
+	},
+	{
+		name: "TimeStamp",
+		get:  func(p project.Project) string { return p.TimeStamp },
+		set:  func(p *project.Project, value string) error { p.TimeStamp = value; return nil },
+	},
+	{
+		name: "Tags",
+		get:  func(p project.Project) string { return strings.Join(p.Tags, LIST_SEPARATOR) },
+		set: func(p *project.Project, value string) error {
+			p.Tags = project.NormalizeTags(strings.Split(value, LIST_SEPARATOR))
+			return nil
+		},
+	},
+	{
+		name: "Pinned",
+		get: func(p project.Project) string {
+			if p.Pinned == 0 {
+				return ""
+			}
+			return strconv.Itoa(p.Pinned)
+		},
+		set: func(p *project.Project, value string) error {
+			if value == "" {
+				p.Pinned = 0
+				return nil
+			}
+			pinned, err := strconv.Atoi(value)
+			if err != nil || pinned < 0 {
+				return fmt.Errorf("pinned %q is not a place like 1", value)
+			}
+			p.Pinned = pinned
+			return nil
+		},
+	},
+	{
+		name: "Notes",
+		get:  func(p project.Project) string { return p.Notes },
+		set:  func(p *project.Project, value string) error { p.Notes = value; return nil },
+	},
+	{
+		// One item per line, done items start with [x]
+		name: "Checklist",
+		get: func(p project.Project) string {
+			lines := make([]string, len(p.Checklist))
+			for i, item := range p.Checklist {
+				lines[i] = item.String()
+			}
+			return strings.Join(lines, "\n")
+		},
+		set: func(p *project.Project, value string) error {
+			p.Checklist = nil
+			for _, line := range strings.Split(value, "\n") {
+				if item := project.ParseChecklistItem(line); item.Text != "" {
+					p.Checklist = append(p.Checklist, item)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// One name=value per line, sorted by name
+		name: "Fields",
+		get: func(p project.Project) string {
+			lines := make([]string, 0, len(p.Fields))
+			for name, value := range p.Fields {
+				lines = append(lines, name+"="+value)
+			}
+			sort.Strings(lines)
+			return strings.Join(lines, "\n")
+		},
+		set: func(p *project.Project, value string) error {
+			p.Fields = nil
+			for _, line := range strings.Split(value, "\n") {
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+				name, field_value, err := project.ParseFieldAssignment(line)
+				if err != nil {
+					return err
+				}
+				// Checked against the schema like fields set with pm add
+				if err := project.SetField(p, name, field_value); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Archiving goes through pm archive, which also packs the directory
+		name: "Archived",
+		get:  func(p project.Project) string { return p.Archived },
+	},
+	{
+		// Restoring unpacks and deletes this file, so it is never imported
+		name: "ArchiveFile",
+		get:  func(p project.Project) string { return p.ArchiveFile },
+	},
+}
+
+// ColumnNames returns the names of every exportable field, in export order.
+func ColumnNames() []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.name
+	}
+
+	return names
+}
+
+func findColumn(name string) (column, bool) {
+	for _, col := range columns {
+		if strings.EqualFold(col.name, name) {
+			return col, true
+		}
+	}
+
+	return column{}, false
+}
+
+// selectColumns returns the columns with the given names, or every column
+// if names is empty.
+func selectColumns(names []string) ([]column, error) {
+	if len(names) == 0 {
+		return columns, nil
+	}
+
+	var selected []column
+	for _, name := range names {
+		col, ok := findColumn(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", name, strings.Join(ColumnNames(), ", "))
+		}
+		selected = append(selected, col)
+	}
+
+	return selected, nil
+}
+
+/*
+ParseMapping parses a field mapping like "Project Name=Name,Folder=Path".
+
+Parameters:
+- mapping: Comma separated source=Field pairs. Field must be a Project field name.
+
+Returns:
+- map[string]string: The Project field for every source key, keyed by the lower cased source key.
+- error: An error if a pair is malformed or names an unknown field.
+*/
+func ParseMapping(mapping string) (map[string]string, error) {
+	parsed := make(map[string]string)
+
+	if strings.TrimSpace(mapping) == "" {
+		return parsed, nil
+	}
+
+	for _, pair := range strings.Split(mapping, ",") {
+		source, field, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("field mapping %q is not in the form source=Field", pair)
+		}
+
+		col, ok := findColumn(strings.TrimSpace(field))
+		if !ok {
+			return nil, fmt.Errorf("field mapping %q: unknown field %q, expected one of %s", pair, field, strings.Join(ColumnNames(), ", "))
+		}
+
+		if col.set == nil {
+			return nil, fmt.Errorf("field mapping %q: %s can't be imported", pair, col.name)
+		}
+
+		parsed[strings.ToLower(strings.TrimSpace(source))] = col.name
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,296 @@
+package import_export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FORMAT_JSON = "json"
+	FORMAT_CSV  = "csv"
+	FORMAT_YAML = "yaml"
+	FORMAT_TOML = "toml"
+)
+
+var Formats = []string{FORMAT_JSON, FORMAT_CSV, FORMAT_YAML, FORMAT_TOML}
+
+// LIST_SEPARATOR joins list values, which every format stores as one string.
+const LIST_SEPARATOR = ";"
+
+// Record is one imported entry: its keys as they are in the source file
+// and their values as strings.
+type Record map[string]string
+
+// FormatFromPath guesses the format of a file from its extension.
+func FormatFromPath(path string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FORMAT_JSON, true
+	case ".csv":
+		return FORMAT_CSV, true
+	case ".yaml", ".yml":
+		return FORMAT_YAML, true
+	case ".toml":
+		return FORMAT_TOML, true
+	}
+
+	return "", false
+}
+
+/*
+Export writes projects to w in the given format.
+
+Parameters:
+- w: Where to write to.
+- projects: The projects to export.
+- format: One of Formats.
+- field_names: The fields to export, in order. Empty means every field.
+
+Returns:
+- error: An error if the format or a field is unknown or writing failed.
+*/
+func Export(w io.Writer, projects []project.Project, format string, field_names []string) error {
+	cols, err := selectColumns(field_names)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(projects))
+	for i, p := range projects {
+		for _, col := range cols {
+			rows[i] = append(rows[i], col.get(p))
+		}
+	}
+
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.name
+	}
+
+	switch format {
+	case FORMAT_JSON:
+		return exportJSON(w, names, rows)
+	case FORMAT_CSV:
+		return exportCSV(w, names, rows)
+	case FORMAT_YAML:
+		return exportYAML(w, names, rows)
+	case FORMAT_TOML:
+		return exportTOML(w, names, rows)
+	}
+
+	return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// exportJSON writes an array of objects, keeping the field order.
+func exportJSON(w io.Writer, names []string, rows [][]string) error {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("[")
+	for i, row := range rows {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("\n  {")
+		for j, value := range row {
+			if j > 0 {
+				buffer.WriteString(",")
+			}
+			key, _ := json.Marshal(names[j])
+			encoded, _ := json.Marshal(value)
+			fmt.Fprintf(&buffer, "\n    %s: %s", key, encoded)
+		}
+		buffer.WriteString("\n  }")
+	}
+	buffer.WriteString("\n]\n")
+
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+func exportCSV(w io.Writer, names []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(names); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+// exportYAML writes a sequence of mappings, keeping the field order.
+func exportYAML(w io.Writer, names []string, rows [][]string) error {
+	document := &yaml.Node{Kind: yaml.SequenceNode}
+
+	for _, row := range rows {
+		mapping := &yaml.Node{Kind: yaml.MappingNode}
+		for j, value := range row {
+			mapping.Content = append(mapping.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: names[j]},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: value, Tag: "!!str"},
+			)
+		}
+		document.Content = append(document.Content, mapping)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+// exportTOML writes one [[projects]] table per project.
+func exportTOML(w io.Writer, names []string, rows [][]string) error {
+	tables := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		tables[i] = make(map[string]string, len(row))
+		for j, value := range row {
+			tables[i][names[j]] = value
+		}
+	}
+
+	return toml.NewEncoder(w).Encode(map[string][]map[string]string{"projects": tables})
+}
+
+/*
+Parse reads records in the given format from r.
+
+JSON and YAML may hold a list of entries or an object with a "projects"
+list, so a projects file of the manager itself can be imported. TOML needs
+the [[projects]] tables written by Export. CSV needs a header row.
+
+Parameters:
+- r: What to read from.
+- format: One of Formats.
+
+Returns:
+- []Record: The entries in file order.
+- error: An error if the input does not parse.
+*/
+func Parse(r io.Reader, format string) ([]Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read import: %w", err)
+	}
+
+	var decoded interface{}
+
+	switch format {
+	case FORMAT_CSV:
+		return parseCSV(data)
+	case FORMAT_JSON:
+		err = json.Unmarshal(data, &decoded)
+	case FORMAT_YAML:
+		err = yaml.Unmarshal(data, &decoded)
+	case FORMAT_TOML:
+		var table map[string]interface{}
+		_, err = toml.Decode(string(data), &table)
+		decoded = table
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", format, err)
+	}
+
+	return recordsFromValue(decoded)
+}
+
+func parseCSV(data []byte) ([]Record, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse csv: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	header := rows[0]
+	var records []Record
+
+	for _, row := range rows[1:] {
+		record := make(Record, len(header))
+		for i, key := range header {
+			if i < len(row) {
+				record[strings.TrimSpace(key)] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// recordsFromValue turns a decoded JSON, YAML or TOML document into records.
+func recordsFromValue(value interface{}) ([]Record, error) {
+	var entries []interface{}
+
+	switch v := value.(type) {
+	case []interface{}:
+		entries = v
+	case []map[string]interface{}:
+		for _, entry := range v {
+			entries = append(entries, entry)
+		}
+	case map[string]interface{}:
+		return recordsFromValue(v["projects"])
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("expected a list of projects, got %T", value)
+	}
+
+	var records []Record
+
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("entry %d is not an object", i+1)
+		}
+
+		record := make(Record, len(fields))
+		for key, field := range fields {
+			record[key] = stringifyValue(field)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func stringifyValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, part := range v {
+			parts[i] = stringifyValue(part)
+		}
+		return strings.Join(parts, LIST_SEPARATOR)
+	}
+
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,267 @@
+package import_export
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+const (
+	ACTION_ADD    = "add"
+	ACTION_UPDATE = "update"
+	ACTION_SKIP   = "skip"
+)
+
+// ImportAction is what importing one entry will do to the registry.
+type ImportAction struct {
+	Action   string
+	Project  project.Project
+	Existing *project.Project
+	Reason   string
+}
+
+func (a ImportAction) String() string {
+	switch a.Action {
+	case ACTION_ADD:
+		return fmt.Sprintf("add    %s (%s)", a.Project.Name, a.Project.Path)
+	case ACTION_UPDATE:
+		return fmt.Sprintf("update %s (%s): %s", a.Existing.Name, a.Existing.Path, a.Reason)
+	}
+
+	return fmt.Sprintf("skip   %s (%s): %s", a.Project.Name, a.Project.Path, a.Reason)
+}
+
+/*
+MapRecords turns imported records into projects.
+
+Parameters:
+- records: The parsed entries.
+- mapping: Source key to Project field, as returned by ParseMapping. Other keys match the field of the same name, ignoring case.
+
+Returns:
+- []project.Project: One project per record, without an ID unless the record had one.
+- []error: For every record, the first value that could not be set, or nil.
+- []string: The source keys that did not map to any field that can be imported, sorted.
+*/
+func MapRecords(records []Record, mapping map[string]string) ([]project.Project, []error, []string) {
+	var projects []project.Project
+	var errs []error
+	unmapped := make(map[string]bool)
+
+	for _, record := range records {
+		var p project.Project
+		var record_err error
+
+		// Sorted keys, so the same record always reports the same error
+		keys := make([]string, 0, len(record))
+		for key := range record {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := record[key]
+			field, ok := mapping[strings.ToLower(key)]
+			if !ok {
+				field = key
+			}
+
+			col, ok := findColumn(field)
+			if !ok || col.set == nil {
+				unmapped[key] = true
+				continue
+			}
+
+			if err := col.set(&p, strings.TrimSpace(value)); err != nil && record_err == nil {
+				record_err = err
+			}
+		}
+
+		projects = append(projects, p)
+		errs = append(errs, record_err)
+	}
+
+	var unmapped_keys []string
+	for key := range unmapped {
+		unmapped_keys = append(unmapped_keys, key)
+	}
+	sort.Strings(unmapped_keys)
+
+	return projects, errs, unmapped_keys
+}
+
+/*
+PlanImport decides for every incoming project whether it is added, updates an
+existing project or is skipped.
+
+An incoming project updates the existing one with the same ID, or failing
+that the one with the same path. Otherwise it is added, unless its name is
+already taken according to CheckDuplicateNames. Incoming projects are
+checked against each other the same way.
+
+Parameters:
+- existing: The current registry.
+- incoming: The projects to import.
+- errs: The errors of the incoming projects, as returned by MapRecords. A project with an error is skipped with it as the reason. May be nil.
+
+Returns:
+- []ImportAction: One action per incoming project, in order.
+*/
+func PlanImport(existing []project.Project, incoming []project.Project, errs []error) []ImportAction {
+	return planImport(existing, incoming, errs, true)
+}
+
+// planImport is PlanImport, with matches skipped instead of updated unless
+// update is set.
+func planImport(existing []project.Project, incoming []project.Project, errs []error, update bool) []ImportAction {
+	var actions []ImportAction
+
+	registry := append([]project.Project(nil), existing...)
+
+	for i, p := range incoming {
+		if i < len(errs) && errs[i] != nil {
+			actions = append(actions, ImportAction{Action: ACTION_SKIP, Project: p, Reason: errs[i].Error()})
+			continue
+		}
+
+		if p.Name == "" || p.Path == "" {
+			actions = append(actions, ImportAction{Action: ACTION_SKIP, Project: p, Reason: "name and path are required"})
+			continue
+		}
+
+		match := findMatch(registry, p)
+		if match < 0 {
+			if !project.CheckDuplicateNames(&registry, p.Name) {
+				actions = append(actions, ImportAction{Action: ACTION_SKIP, Project: p, Reason: "another project already has this name"})
+				continue
+			}
+
+			if p.ID == "" {
+				p.ID = project.NewProjectID()
+			}
+			if p.TimeStamp == "" {
+				p.TimeStamp = time.Now().Format(time.RFC3339)
+			}
+
+			registry = append(registry, p)
+			actions = append(actions, ImportAction{Action: ACTION_ADD, Project: p})
+			continue
+		}
+
+		current := registry[match]
+		if !update {
+			actions = append(actions, ImportAction{Action: ACTION_SKIP, Project: p, Reason: "already registered as " + current.Name})
+			continue
+		}
+
+		updated, err := mergeImported(current, p)
+		if err != nil {
+			actions = append(actions, ImportAction{Action: ACTION_SKIP, Project: p, Reason: err.Error()})
+			continue
+		}
+
+		fields := changedFieldNames(current, updated)
+		if len(fields) == 0 {
+			actions = append(actions, ImportAction{Action: ACTION_SKIP, Project: p, Reason: "already up to date"})
+			continue
+		}
+
+		if updated.Name != current.Name {
+			others := append(append([]project.Project(nil), registry[:match]...), registry[match+1:]...)
+			if !project.CheckDuplicateNames(&others, updated.Name) {
+				actions = append(actions, ImportAction{Action: ACTION_SKIP, Project: p, Reason: "another project already has this name"})
+				continue
+			}
+		}
+
+		registry[match] = updated
+		actions = append(actions, ImportAction{
+			Action:   ACTION_UPDATE,
+			Project:  updated,
+			Existing: &current,
+			Reason:   strings.Join(fields, ", "),
+		})
+	}
+
+	return actions
+}
+
+// findMatch returns the index of the project p refers to, by ID first and
+// path second, or -1.
+func findMatch(registry []project.Project, p project.Project) int {
+	if p.ID != "" {
+		for i := range registry {
+			if registry[i].ID == p.ID {
+				return i
+			}
+		}
+	}
+
+	for i := range registry {
+		if samePath(registry[i].Path, p.Path) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func samePath(a, b string) bool {
+	return filepath.Clean(filepath.FromSlash(a)) == filepath.Clean(filepath.FromSlash(b))
+}
+
+// mergeImported applies the non-empty fields of imported onto current.
+// The ID and creation time of current are kept.
+func mergeImported(current, imported project.Project) (project.Project, error) {
+	merged := current
+
+	for _, col := range columns {
+		if col.name == "ID" || col.name == "TimeStamp" || col.set == nil {
+			continue
+		}
+		if value := col.get(imported); value != "" {
+			if err := col.set(&merged, value); err != nil {
+				return current, err
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+func changedFieldNames(a, b project.Project) []string {
+	var fields []string
+
+	for _, col := range columns {
+		if col.get(a) != col.get(b) {
+			fields = append(fields, col.name)
+		}
+	}
+
+	return fields
+}
+
+// ApplyImport writes the add and update actions to the store.
+func ApplyImport(store project.ProjectStore, actions []ImportAction) (added, updated int, err error) {
+	for _, action := range actions {
+		if action.Action == ACTION_SKIP {
+			continue
+		}
+
+		if err := store.Put(action.Project); err != nil {
+			return added, updated, fmt.Errorf("import %s: %w", action.Project.Name, err)
+		}
+
+		if action.Action == ACTION_ADD {
+			added++
+		} else {
+			updated++
+		}
+	}
+
+	return added, updated, nil
+}
@@ -0,0 +1,65 @@
+package import_export
+
+import (
+	"strings"
+	"testing"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+func TestPlanImportSkipsInvalidRecords(t *testing.T) {
+	project.SetFieldSchema([]project.FieldDef{{Name: "status", Type: project.FIELD_ENUM, Values: []string{"active", "done"}}})
+	defer project.SetFieldSchema(nil)
+
+	records := []Record{
+		{"Name": "good", "Path": "/work/good", "Pinned": "1", "Fields": "Status=Active"},
+		{"Name": "pinned", "Path": "/work/pinned", "Pinned": "first"},
+		{"Name": "unknown", "Path": "/work/unknown", "Fields": "colour=red"},
+		{"Name": "enum", "Path": "/work/enum", "Fields": "status=nope"},
+		{"Name": "existing", "Path": "/work/existing", "Fields": "status=later"},
+	}
+	existing := []project.Project{{ID: "01HZAAAAAAAAAAAAAAAAAAAAAA", Name: "existing", Path: "/work/existing"}}
+
+	incoming, errs, _ := MapRecords(records, nil)
+	actions := PlanImport(existing, incoming, errs)
+
+	want := []struct {
+		action string
+		reason string
+	}{
+		{ACTION_ADD, ""},
+		{ACTION_SKIP, `pinned "first"`},
+		{ACTION_SKIP, `unknown field "colour"`},
+		{ACTION_SKIP, `"nope" is not one of`},
+		{ACTION_SKIP, `"later" is not one of`},
+	}
+
+	if len(actions) != len(want) {
+		t.Fatalf("got %d actions, want %d", len(actions), len(want))
+	}
+	for i, w := range want {
+		if actions[i].Action != w.action || !strings.Contains(actions[i].Reason, w.reason) {
+			t.Errorf("action %d = %s, want %s with reason containing %q", i, actions[i], w.action, w.reason)
+		}
+	}
+
+	if fields := actions[0].Project.Fields; fields["status"] != "active" {
+		t.Errorf("imported fields = %v, want status=active", fields)
+	}
+}
+
+func TestImportIgnoresArchiveColumns(t *testing.T) {
+	records := []Record{{"Name": "alpha", "Path": "/work/alpha", "Archived": "2024-03-04T05:06:07Z", "ArchiveFile": "/home/user/.bashrc"}}
+
+	incoming, _, unmapped := MapRecords(records, nil)
+	if incoming[0].Archived != "" || incoming[0].ArchiveFile != "" {
+		t.Fatalf("imported archive state %+v", incoming[0])
+	}
+	if len(unmapped) != 2 {
+		t.Fatalf("unmapped = %v, want Archived and ArchiveFile", unmapped)
+	}
+
+	if _, err := ParseMapping("file=ArchiveFile"); err == nil {
+		t.Fatal("mapping onto ArchiveFile was accepted")
+	}
+}
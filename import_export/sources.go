@@ -0,0 +1,351 @@
+package import_export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	path_manager "github.com/yur4uwe/cmd-project-manager/manage_paths"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// Tools whose project lists can be imported.
+const (
+	SOURCE_VSCODE_PM     = "vscode-pm"
+	SOURCE_VSCODE_RECENT = "vscode-recent"
+	SOURCE_ZOXIDE        = "zoxide"
+)
+
+var Sources = []string{SOURCE_VSCODE_PM, SOURCE_VSCODE_RECENT, SOURCE_ZOXIDE}
+
+// Candidate is a project found in another tool, with its usage history if
+// the tool keeps one.
+type Candidate struct {
+	Project project.Project
+	History *path_manager.RecentPath
+}
+
+/*
+DefaultSourcePath returns where a tool keeps its project list.
+
+Parameters:
+- source: One of Sources.
+
+Returns:
+- string: The file to read, or "" if the list is not read from a file. Zoxide is queried by running it.
+*/
+func DefaultSourcePath(source string) string {
+	switch source {
+	case SOURCE_VSCODE_PM:
+		return filepath.Join(vscodeUserDir(), "globalStorage", "alefragnani.project-manager", "projects.json")
+	case SOURCE_VSCODE_RECENT:
+		return filepath.Join(vscodeUserDir(), "globalStorage", "storage.json")
+	}
+
+	return ""
+}
+
+// vscodeUserDir returns the User settings directory of VS Code.
+func vscodeUserDir() string {
+	home, _ := os.UserHomeDir()
+
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("APPDATA"), "Code", "User")
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "Code", "User")
+	}
+
+	config_home := os.Getenv("XDG_CONFIG_HOME")
+	if config_home == "" {
+		config_home = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(config_home, "Code", "User")
+}
+
+/*
+ReadSource reads the project list of a tool.
+
+Parameters:
+- source: One of Sources.
+- path: The file to read, "-" for stdin or "" for DefaultSourcePath. For zoxide "" runs `zoxide query -ls`.
+
+Returns:
+- []Candidate: The projects found, in the order the tool lists them.
+- error: An error if the list can't be read or parsed.
+*/
+func ReadSource(source, path string) ([]Candidate, error) {
+	if !isSource(source) {
+		return nil, unknownSourceError(source)
+	}
+
+	if path == "" {
+		path = DefaultSourcePath(source)
+	}
+
+	var data []byte
+	var err error
+
+	switch {
+	case path == "" && source == SOURCE_ZOXIDE:
+		data, err = exec.Command("zoxide", "query", "-ls").Output()
+	case path == "-":
+		data, err = io.ReadAll(os.Stdin)
+	default:
+		data, err = os.ReadFile(path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read %s list: %w", source, err)
+	}
+
+	return ParseSource(data, source)
+}
+
+// ParseSource turns the raw project list of a tool into candidates.
+func ParseSource(data []byte, source string) ([]Candidate, error) {
+	switch source {
+	case SOURCE_VSCODE_PM:
+		return parseVSCodeProjectManager(data)
+	case SOURCE_VSCODE_RECENT:
+		return parseVSCodeStorage(data)
+	case SOURCE_ZOXIDE:
+		return parseZoxide(data)
+	}
+
+	return nil, unknownSourceError(source)
+}
+
+func isSource(source string) bool {
+	for _, known := range Sources {
+		if known == source {
+			return true
+		}
+	}
+
+	return false
+}
+
+func unknownSourceError(source string) error {
+	return fmt.Errorf("unknown source %q, expected one of %s", source, strings.Join(Sources, ", "))
+}
+
+// parseVSCodeProjectManager reads the projects.json of the Project Manager
+// extension. Disabled and remote projects are left out.
+func parseVSCodeProjectManager(data []byte) ([]Candidate, error) {
+	var entries []struct {
+		Name     string   `json:"name"`
+		RootPath string   `json:"rootPath"`
+		Enabled  *bool    `json:"enabled"`
+		Tags     []string `json:"tags"`
+	}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse vscode project manager list: %w", err)
+	}
+
+	var candidates []Candidate
+
+	for _, entry := range entries {
+		if entry.Enabled != nil && !*entry.Enabled {
+			continue
+		}
+
+		path, ok := localPath(entry.RootPath)
+		if !ok {
+			continue
+		}
+
+		name := entry.Name
+		if name == "" {
+			name = filepath.Base(path)
+		}
+
+		candidate := Candidate{Project: newCandidateProject(name, path)}
+		candidate.Project.Tags = project.NormalizeTags(entry.Tags)
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// parseVSCodeStorage reads the recently opened folders from the
+// storage.json of VS Code, most recent first. Files and workspaces are left
+// out. VS Code doesn't keep open counts or times, so every folder counts as
+// opened once.
+func parseVSCodeStorage(data []byte) ([]Candidate, error) {
+	var storage struct {
+		OpenedPathsList struct {
+			Entries []struct {
+				FolderURI string `json:"folderUri"`
+			} `json:"entries"`
+			Workspaces3 []json.RawMessage `json:"workspaces3"`
+		} `json:"openedPathsList"`
+	}
+
+	if err := json.Unmarshal(data, &storage); err != nil {
+		return nil, fmt.Errorf("parse vscode storage: %w", err)
+	}
+
+	var uris []string
+	for _, entry := range storage.OpenedPathsList.Entries {
+		uris = append(uris, entry.FolderURI)
+	}
+
+	// Versions before 1.44 kept folders in workspaces3, either as a plain
+	// URI or as an object with a folderUri.
+	for _, raw := range storage.OpenedPathsList.Workspaces3 {
+		var uri string
+		if json.Unmarshal(raw, &uri) == nil {
+			uris = append(uris, uri)
+			continue
+		}
+
+		var folder struct {
+			FolderURI string `json:"folderUri"`
+		}
+		if json.Unmarshal(raw, &folder) == nil {
+			uris = append(uris, folder.FolderURI)
+		}
+	}
+
+	var candidates []Candidate
+
+	for _, uri := range uris {
+		path, ok := localPath(uri)
+		if !ok {
+			continue
+		}
+
+		candidates = append(candidates, Candidate{
+			Project: newCandidateProject(filepath.Base(path), path),
+			History: &path_manager.RecentPath{Path: path, TimesOpened: 1},
+		})
+	}
+
+	return candidates, nil
+}
+
+// parseZoxide reads the output of `zoxide query -ls`, one "score path" per
+// line. The rounded score is used as the open count. Lines of
+// `zoxide query -l` without a score count as opened once.
+func parseZoxide(data []byte) ([]Candidate, error) {
+	var candidates []Candidate
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		path := line
+		times_opened := 1
+
+		if score_text, rest, ok := strings.Cut(line, " "); ok {
+			if score, err := strconv.ParseFloat(score_text, 64); err == nil {
+				path = strings.TrimSpace(rest)
+				times_opened = int(math.Max(1, math.Round(score)))
+			}
+		}
+
+		candidates = append(candidates, Candidate{
+			Project: newCandidateProject(filepath.Base(path), path),
+			History: &path_manager.RecentPath{Path: path, TimesOpened: times_opened},
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("parse zoxide list: %w", err)
+	}
+
+	return candidates, nil
+}
+
+func newCandidateProject(name, path string) project.Project {
+	return project.Project{
+		Name:      name,
+		Path:      path,
+		TimeStamp: time.Now().Format(time.RFC3339),
+	}
+}
+
+// localPath turns a path or file:// URI as VS Code writes it into a local
+// path. Remote URIs are not local.
+func localPath(location string) (string, bool) {
+	if location == "" {
+		return "", false
+	}
+
+	if strings.Contains(location, "://") {
+		uri, err := url.Parse(location)
+		if err != nil || uri.Scheme != "file" {
+			return "", false
+		}
+		location = uri.Path
+
+		// file:///c%3A/Users becomes /c:/Users
+		if len(location) > 2 && location[0] == '/' && location[2] == ':' {
+			location = location[1:]
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	for _, prefix := range []string{"$home", "~"} {
+		if strings.HasPrefix(location, prefix) {
+			location = home + location[len(prefix):]
+			break
+		}
+	}
+
+	return filepath.Clean(filepath.FromSlash(location)), true
+}
+
+/*
+PlanSourceImport is PlanImport for candidates from another tool. A
+candidate whose path is already in the registry is skipped instead of
+updating the registered project.
+
+Parameters:
+- existing: The current registry.
+- candidates: The candidates to import.
+
+Returns:
+- []ImportAction: One action per candidate, in order.
+*/
+func PlanSourceImport(existing []project.Project, candidates []Candidate) []ImportAction {
+	incoming := make([]project.Project, len(candidates))
+	for i, candidate := range candidates {
+		incoming[i] = candidate.Project
+	}
+
+	return planImport(existing, incoming, nil, false)
+}
+
+// ImportHistory merges the usage history of candidates into the path history.
+func ImportHistory(candidates []Candidate) {
+	var history []path_manager.RecentPath
+
+	for _, candidate := range candidates {
+		if candidate.History != nil {
+			history = append(history, *candidate.History)
+		}
+	}
+
+	if len(history) > 0 {
+		path_manager.MergeRecentPaths(history)
+	}
+}
@@ -1,33 +1,64 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/eiannone/keyboard"
+	cli "github.com/yur4uwe/cmd-project-manager/cli"
+	config "github.com/yur4uwe/cmd-project-manager/config"
 	display "github.com/yur4uwe/cmd-project-manager/display"
+	path_manager "github.com/yur4uwe/cmd-project-manager/manage_paths"
 	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+	snapshot "github.com/yur4uwe/cmd-project-manager/snapshots"
+	sqlite_store "github.com/yur4uwe/cmd-project-manager/sqlite_store"
 )
 
 const (
-	TERMINATE = iota - 2
+	REDO = iota - 4
+	UNDO
+	TERMINATE
 	MAIN_MENU
 	ADD_PROJECT
 	UPDATE_PROJECT
 	REMOVE_PROJECT
 	LIST_PROJECTS
+	TAGS
+	GROUPS
+	ARCHIVED
+	SNAPSHOTS
 	EXIT_PROGRAM
 )
 
-// TODO: something is wrong again with opening folder in explorer
 // TODO: Clear() issues
 
 func main() {
-	logFilePath := "log.txt"
+	migrate := flag.Bool("migrate", false, "upgrade the projects file to the current format and exit")
+	dry_run := flag.Bool("dry-run", false, "with --migrate, only show what would change")
+	data_dir := flag.String("data-dir", "", "keep the registry, history, journal and snapshots in this directory")
+	line_mode := flag.Bool("line-mode", false, "use numbered menus answered line by line instead of single keys")
+	flag.Parse()
+
+	dirs := config.ResolveDirs(*data_dir)
+
+	// Completion runs on every tab press, so it neither truncates the log of
+	// the last real command nor stops for migrations and prompts
+	if flag.Arg(0) == "__complete" {
+		os.Exit(runCompletion(dirs, flag.Args()))
+	}
+
+	if err := dirs.Create(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not create the data directories:", err)
+		os.Exit(2)
+	}
 
 	// Open the file with the os.O_TRUNC flag to clear its contents and set it for logging
-	logFile, err := os.OpenFile(logFilePath, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	logFile, err := os.OpenFile(dirs.LogFile(), os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
 	if err != nil {
 		log.Fatalf("Failed to open log file: %v", err)
 	}
@@ -37,43 +68,411 @@ func main() {
 
 	log.Println("Program start\n+-------------------+")
 
-	var projects []project.Project = project.ReadProjectsFromFile()
+	if *dry_run && !*migrate {
+		fmt.Fprintln(os.Stderr, "--dry-run can only be used together with --migrate")
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig(dirs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load config:", err)
+		os.Exit(2)
+	}
+
+	if offerLegacyMigration(dirs, cfg, flag.NArg() == 0 && !*migrate) {
+		if cfg, err = loadConfig(dirs); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load config:", err)
+			os.Exit(2)
+		}
+	}
 
-	if err := keyboard.Open(); err != nil {
-		log.Fatal("Error while opening the keyboard: ", err)
+	manager := snapshot.NewManager(cfg.SnapshotDir, cfg.SnapshotKeep)
+	project.SetArchiveDir(cfg.ArchiveDir)
+	project.SetFieldSchema(cfg.Fields)
+	project.SetGroupsFile(cfg.GroupsFile)
+	project.SetWorkspaceDir(cfg.WorkspaceDir)
+	project.SetEditor(cfg.Editor)
+
+	if *migrate {
+		if !*dry_run {
+			snapshotBeforeMigration(cfg, manager)
+		}
+		os.Exit(runMigration(project.NewJSONFileStore(cfg.ProjectsFile), *dry_run))
 	}
 
-	defer keyboard.Close()
-	defer project.SaveProjects(&projects)
+	snapshotBeforeMigration(cfg, manager)
+
+	if *line_mode {
+		display.UseLineMode()
+	}
+
+	base_store, close_store, err := openStore(cfg)
+	var corrupt_err *project.CorruptRegistryError
+	if errors.As(err, &corrupt_err) {
+		// The JSON registry the SQLite store is first filled from is corrupt,
+		// recover it the way the JSON backend does before importing it
+		if flag.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "Could not open project store:", err)
+			fmt.Fprintln(os.Stderr, "Start pm without a command to review and recover it.")
+			os.Exit(1)
+		}
+		if !recoverJSONRegistry(cfg, corrupt_err) {
+			return
+		}
+		base_store, close_store, err = openStore(cfg)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not open project store:", err)
+		log.Println("Error while opening project store: ", err)
+		os.Exit(1)
+	}
+	defer close_store()
+
+	if flag.NArg() > 0 {
+		code := runCommand(cfg, base_store, manager, flag.Args())
+		close_store()
+		logFile.Close()
+		os.Exit(code)
+	}
+
+	defer display.OpenKeyboard()()
+
+	if _, err := base_store.Load(); errors.As(err, &corrupt_err) {
+		if !display.ConfirmRecovery(corrupt_err) {
+			fmt.Println("Projects file left untouched. Exiting...")
+			return
+		}
+		recoverable, ok := base_store.(project.RecoverableStore)
+		if !ok {
+			fmt.Println("This store can't save recovered projects:", corrupt_err)
+			return
+		}
+		if err := recoverable.AcceptRecovered(); err != nil {
+			log.Println("Error while saving recovered projects: ", err)
+			fmt.Println("Could not save recovered projects:", err)
+			return
+		}
+	} else if err != nil {
+		fmt.Println("Could not load projects:", err)
+		log.Println("Error while loading projects: ", err)
+		return
+	}
+
+	store := project.NewJournaledStore(snapshot.NewAutoStore(base_store, manager), cfg.JournalFile)
+
+	if due, err := manager.CountLaunch(cfg.SnapshotEveryLaunches); err != nil {
+		log.Println("Error while counting launches for snapshots: ", err)
+	} else if due {
+		if _, err := manager.Capture("scheduled", "", true, base_store); err != nil {
+			log.Println("Error while taking scheduled snapshot: ", err)
+		}
+	}
 
 	display.Clear()
 
 outerLoop:
 	for {
-		selected := display.MainMenu()
+		projects, err := store.List()
+		if err != nil {
+			log.Println("Error while listing pinned projects: ", err)
+		}
+		pinned := project.PinnedProjects(projects)
+
+		selected := display.MainMenu(pinned)
 
 		switch selected {
 		case TERMINATE, EXIT_PROGRAM:
 			fmt.Println("Exiting...")
 			break outerLoop
+		case UNDO:
+			err = undoStep("undo", "Undid", store.Undo)
+		case REDO:
+			err = undoStep("redo", "Redid", store.Redo)
 		case ADD_PROJECT:
 			display.Clear()
-			display.AddProjectInterface(&projects)
-			display.Clear()
+			err = display.AddProjectInterface(store)
 		case UPDATE_PROJECT:
 			display.Clear()
-			projects = display.UpdateProject(projects)
-			display.Clear()
+			err = display.UpdateProject(store)
 		case REMOVE_PROJECT:
 			display.Clear()
-			projects = display.RemoveProject(projects)
-			display.Clear()
+			err = display.RemoveProject(store)
 		case LIST_PROJECTS:
 			display.Clear()
-			display.ProjectsList(projects)
+			err = display.ProjectsList(store)
+		case TAGS:
 			display.Clear()
-		default:
+			err = display.TagsScreen(store)
+		case GROUPS:
 			display.Clear()
+			err = display.GroupsScreen(store)
+		case ARCHIVED:
+			display.Clear()
+			err = display.ArchivedScreen(store)
+		case SNAPSHOTS:
+			display.Clear()
+			err = display.SnapshotsScreen(store, manager)
+		default:
+			if index := display.PINNED_RESULT - selected; index >= 0 && index < len(pinned) {
+				display.Clear()
+				err = display.ProjectOptions(store, pinned[index])
+			}
+		}
+
+		if err != nil {
+			display.ShowError(err)
+		}
+
+		display.ShowConflicts(store.TakeConflicts())
+
+		display.Clear()
+	}
+}
+
+// undoStep runs an undo or redo from the main menu and shows what it did.
+func undoStep(name, done string, step func() (project.JournalEntry, error)) error {
+	entry, err := step()
+	if errors.Is(err, project.ErrNothingToUndo) || errors.Is(err, project.ErrNothingToRedo) {
+		display.ShowMessage("Nothing to " + name)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	display.ShowMessage(fmt.Sprintf("%s: %s", done, entry))
+
+	return nil
+}
+
+// runCommand loads the registry and runs a non-interactive subcommand.
+// Returns the process exit code.
+func runCommand(cfg config.Config, base_store project.ProjectStore, manager *snapshot.Manager, args []string) int {
+	if !cli.IsCommand(args[0]) {
+		return cli.Run(nil, args)
+	}
+
+	if _, err := base_store.Load(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load projects:", err)
+		var corrupt_err *project.CorruptRegistryError
+		if errors.As(err, &corrupt_err) {
+			fmt.Fprintln(os.Stderr, "Start pm without a command to review and recover it.")
+		}
+		return cli.EXIT_FAILURE
+	}
+
+	env := &cli.Env{
+		Config: cfg,
+		Store:  project.NewJournaledStore(snapshot.NewAutoStore(base_store, manager), cfg.JournalFile),
+	}
+	if querier, ok := base_store.(project.ProjectQuerier); ok {
+		env.Query = querier
+	}
+
+	return cli.Run(env, args)
+}
+
+// runCompletion answers pm __complete from the registry as it is on disk,
+// without writing anything. Returns the process exit code.
+func runCompletion(dirs config.Dirs, args []string) int {
+	log.SetOutput(io.Discard)
+
+	cfg, err := loadConfig(dirs)
+	if err != nil {
+		return cli.EXIT_FAILURE
+	}
+
+	project.SetFieldSchema(cfg.Fields)
+	project.SetGroupsFile(cfg.GroupsFile)
+
+	env := &cli.Env{Config: cfg, Store: project.NewMemoryStore()}
+
+	if cfg.Backend == config.BACKEND_SQLITE && fileExists(cfg.SQLiteFile) {
+		if db, err := sqlite_store.Open(cfg.SQLiteFile); err == nil {
+			defer db.Close()
+			env.Store = db.Projects()
+		}
+	} else if projects_json, err := os.ReadFile(cfg.ProjectsFile); err == nil {
+		// Decoded in memory, so an old or broken file is left as it is
+		if projects, _, err := project.DecodeRegistry(projects_json); err == nil {
+			env.Store = project.NewMemoryStore(projects...)
 		}
 	}
+
+	return cli.Run(env, args)
+}
+
+// loadConfig reads the configuration from the config directory and points
+// its relative paths at the data directory.
+func loadConfig(dirs config.Dirs) (config.Config, error) {
+	cfg, err := config.Load(dirs.ConfigFile())
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.InDataDir(dirs.Data)
+
+	return cfg, nil
+}
+
+/*
+offerLegacyMigration looks for a registry that an older version kept in the
+working directory or next to the executable, while the data directory has
+none yet. The user is asked once whether to copy it over.
+
+Parameters:
+- dirs: The resolved directories.
+- cfg: The current configuration.
+- interactive: Whether the user can be asked. Otherwise only a hint is printed.
+
+Returns:
+- bool: Whether the legacy files were copied, so the configuration has to be read again.
+*/
+func offerLegacyMigration(dirs config.Dirs, cfg config.Config, interactive bool) bool {
+	checked_file := filepath.Join(dirs.State, config.LEGACY_CHECKED_FILE)
+	if fileExists(checked_file) || fileExists(cfg.ProjectsFile) || fileExists(cfg.SQLiteFile) {
+		return false
+	}
+
+	search_dirs := []string{}
+	if cwd, err := os.Getwd(); err == nil {
+		search_dirs = append(search_dirs, cwd)
+	}
+	if executable, err := os.Executable(); err == nil {
+		search_dirs = append(search_dirs, filepath.Dir(executable))
+	}
+
+	legacy, found := config.FindLegacy(search_dirs...)
+	if !found || filepath.Clean(legacy.Dir) == filepath.Clean(dirs.Data) {
+		return false
+	}
+
+	if !interactive {
+		fmt.Fprintf(os.Stderr, "Found projects of an older version in %s, start pm without a command to move them to %s\n", legacy.Dir, dirs.Data)
+		return false
+	}
+
+	fmt.Printf("Found projects of an older version in %s:\n  %s\n", legacy.Dir, strings.Join(legacy.Files, "\n  "))
+	fmt.Printf("Copy them to %s? The old files are left where they are. [y/N] ", dirs.Data)
+
+	answer, _ := display.ReadLine()
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	if err := os.WriteFile(checked_file, []byte(legacy.Dir+"\n"), 0644); err != nil {
+		log.Println("Error while recording the legacy file check: ", err)
+	}
+
+	if answer != "y" && answer != "yes" {
+		return false
+	}
+
+	if err := legacy.CopyInto(dirs); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not copy the old files:", err)
+		log.Println("Error while copying legacy files: ", err)
+		return false
+	}
+
+	log.Println("Copied legacy files from ", legacy.Dir)
+	return true
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// openStore opens the project store of the configured backend and points
+// the path history at the same backend. The returned func closes it.
+func openStore(cfg config.Config) (project.ProjectStore, func(), error) {
+	if cfg.Backend != config.BACKEND_SQLITE {
+		path_manager.SetStore(path_manager.NewJSONHistoryStore(cfg.HistoryFile))
+		return project.NewJSONFileStore(cfg.ProjectsFile), func() {}, nil
+	}
+
+	db, err := sqlite_store.Open(cfg.SQLiteFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	imported, err := db.ImportJSON(project.NewJSONFileStore(cfg.ProjectsFile), path_manager.NewJSONHistoryStore(cfg.HistoryFile))
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("import JSON registry: %w", err)
+	}
+	if imported {
+		log.Println("Imported JSON registry into ", cfg.SQLiteFile)
+	}
+
+	path_manager.SetStore(db.History())
+
+	return db.Projects(), func() { db.Close() }, nil
+}
+
+// recoverJSONRegistry asks whether to continue with the projects that could
+// be recovered from a corrupt JSON registry, and writes them back to it if
+// so. Reports whether the user agreed and the file was written.
+func recoverJSONRegistry(cfg config.Config, corrupt_err *project.CorruptRegistryError) bool {
+	close_keyboard := display.OpenKeyboard()
+	confirmed := display.ConfirmRecovery(corrupt_err)
+	close_keyboard()
+
+	if !confirmed {
+		fmt.Println("Projects file left untouched. Exiting...")
+		return false
+	}
+
+	json_store := project.NewJSONFileStore(cfg.ProjectsFile)
+	if _, err := json_store.Load(); err != nil && !errors.As(err, &corrupt_err) {
+		fmt.Println("Could not load projects:", err)
+		return false
+	}
+	if err := json_store.AcceptRecovered(); err != nil {
+		log.Println("Error while saving recovered projects: ", err)
+		fmt.Println("Could not save recovered projects:", err)
+		return false
+	}
+
+	return true
+}
+
+// snapshotBeforeMigration keeps a copy of the projects file as it is before
+// loading upgrades it to the current format.
+func snapshotBeforeMigration(cfg config.Config, manager *snapshot.Manager) {
+	report, err := project.NewJSONFileStore(cfg.ProjectsFile).Migrate(true)
+	if err != nil || !report.NeedsMigration() {
+		return
+	}
+
+	projects_json, err := os.ReadFile(cfg.ProjectsFile)
+	if err != nil {
+		log.Println("Error while reading projects file for snapshot: ", err)
+		return
+	}
+
+	history_json, err := os.ReadFile(cfg.HistoryFile)
+	if err != nil && !os.IsNotExist(err) {
+		log.Println("Error while reading path history for snapshot: ", err)
+	}
+
+	reason := fmt.Sprintf("upgrade from version %d to %d", report.FromVersion, report.ToVersion)
+	if _, err := manager.Create("before-migrate", reason, true, projects_json, history_json); err != nil {
+		log.Println("Error while taking snapshot before migration: ", err)
+	}
+}
+
+// runMigration upgrades the projects file and prints what changed.
+// Returns the process exit code.
+func runMigration(store *project.JSONFileStore, dry_run bool) int {
+	report, err := store.Migrate(dry_run)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Migration failed:", err)
+		return 1
+	}
+
+	if dry_run && report.NeedsMigration() {
+		fmt.Print("Dry run, nothing was written.\n", report)
+	} else {
+		fmt.Print(report)
+	}
+
+	return 0
 }
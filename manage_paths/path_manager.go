@@ -6,6 +6,8 @@ import (
 	"log"
 	"os"
 	"time"
+
+	file_utils "github.com/yur4uwe/cmd-project-manager/file_utils"
 )
 
 type RecentPath struct {
@@ -14,13 +16,29 @@ type RecentPath struct {
 	TimesOpened int    `json:"TimesOpened"`
 }
 
-func ReadRecentPathsFromFile() ([]RecentPath, error) {
-	fmt.Println("Read Recent Paths From File")
+// HistoryStore is the storage backend for the directory history.
+type HistoryStore interface {
+	Load() ([]RecentPath, error)
+	Save(recent_paths []RecentPath) error
+}
 
-	file, err := os.ReadFile(".directory_history.json")
-	if err != nil {
+// JSONHistoryStore keeps the directory history in a JSON file.
+type JSONHistoryStore struct {
+	path string
+}
+
+func NewJSONHistoryStore(path string) *JSONHistoryStore {
+	return &JSONHistoryStore{path: path}
+}
+
+// Load reads the history from disk. A missing file is an empty history.
+func (s *JSONHistoryStore) Load() ([]RecentPath, error) {
+	file, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
 		log.Println("Error while reading path history file: ", err)
-		return nil, fmt.Errorf("os: path history file doesn't exist:\n %w", err)
+		return nil, fmt.Errorf("os: failed to read path history file:\n %w", err)
 	}
 
 	var recent_paths []RecentPath
@@ -33,8 +51,35 @@ func ReadRecentPathsFromFile() ([]RecentPath, error) {
 	return recent_paths, nil
 }
 
+func (s *JSONHistoryStore) Save(recent_paths []RecentPath) error {
+	recentPathsJSON, err := json.MarshalIndent(recent_paths, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json: failed to marshal path history:\n %w", err)
+	}
+
+	err = file_utils.WriteFileAtomic(s.path, recentPathsJSON, 0644)
+	if err != nil {
+		return fmt.Errorf("os: failed to write path history file:\n %w", err)
+	}
+
+	return nil
+}
+
+var store HistoryStore = NewJSONHistoryStore(".directory_history.json")
+
+// SetStore switches where the directory history is kept.
+func SetStore(history_store HistoryStore) {
+	store = history_store
+}
+
+func ReadRecentPathsFromFile() ([]RecentPath, error) {
+	log.Println("Read Recent Paths From File")
+
+	return store.Load()
+}
+
 func AddRecentPath(path string) {
-	fmt.Println("Add Recent Path")
+	log.Println("Add Recent Path")
 
 	recent_paths, err := ReadRecentPathsFromFile()
 	if err != nil {
@@ -55,23 +100,21 @@ func AddRecentPath(path string) {
 	SaveRecentPaths(recent_paths)
 }
 
-func SaveRecentPaths(recent_paths []RecentPath) {
-	fmt.Println("Save Recent Paths")
+// SaveRecentPaths replaces the history. Errors are logged as well, for the
+// callers that carry on without the history.
+func SaveRecentPaths(recent_paths []RecentPath) error {
+	log.Println("Save Recent Paths")
 
-	recentPathsJSON, err := json.MarshalIndent(recent_paths, "", "  ")
+	err := store.Save(recent_paths)
 	if err != nil {
-		log.Println("Error while marshaling JSON file: ", err)
-		return
+		log.Println("Error while saving path history: ", err)
 	}
 
-	err = os.WriteFile(".directory_history.json", recentPathsJSON, 0644)
-	if err != nil {
-		log.Println("Error while writing to JSON file: ", err)
-	}
+	return err
 }
 
 func RemoveDuplicatePaths(recent_paths []RecentPath) []RecentPath {
-	fmt.Println("Remove Duplicate Paths")
+	log.Println("Remove Duplicate Paths")
 
 	var new_recent_paths []RecentPath
 
@@ -94,7 +137,7 @@ func RemoveDuplicatePaths(recent_paths []RecentPath) []RecentPath {
 }
 
 func IncrementAccess(path string) {
-	fmt.Println("Increment Access")
+	log.Println("Increment Access")
 
 	recent_paths, err := ReadRecentPathsFromFile()
 	if err != nil {
@@ -102,19 +145,30 @@ func IncrementAccess(path string) {
 		return
 	}
 
+	found := false
+
 	for i, recent_path := range recent_paths {
 		if recent_path.Path == path {
 			recent_paths[i].TimesOpened++
 			recent_paths[i].LastAccess = time.Now().Format(time.RFC3339)
+			found = true
 			break
 		}
 	}
 
+	if !found {
+		recent_paths = append(recent_paths, RecentPath{
+			Path:        path,
+			LastAccess:  time.Now().Format(time.RFC3339),
+			TimesOpened: 1,
+		})
+	}
+
 	SaveRecentPaths(recent_paths)
 }
 
 func GetMostRecentPaths() []string {
-	fmt.Println("Get Most Recent Paths")
+	log.Println("Get Most Recent Paths")
 
 	recent_paths, err := ReadRecentPathsFromFile()
 	if err != nil {
@@ -145,15 +199,84 @@ func GetMostRecentPaths() []string {
 	return recent_paths_strings
 }
 
-func RemovePath(path string) []string {
+// RemovePath drops path from the history, along with how often it was opened.
+func RemovePath(path string) {
+	log.Println("Remove Path")
+
+	recent_paths, err := ReadRecentPathsFromFile()
+	if err != nil {
+		log.Println("read path history: failed to read path history\n", err)
+		return
+	}
+
+	kept := recent_paths[:0]
+	for _, recent_path := range recent_paths {
+		if recent_path.Path != path {
+			kept = append(kept, recent_path)
+		}
+	}
+
+	if len(kept) == len(recent_paths) {
+		return
+	}
+
+	SaveRecentPaths(kept)
+}
+
+// MergeRecentPaths adds paths to the history. A path that is already in it
+// keeps the higher open count and the later access time of the two.
+func MergeRecentPaths(paths []RecentPath) {
+	log.Println("Merge Recent Paths")
 
-	recent_paths := GetMostRecentPaths()
+	recent_paths, err := ReadRecentPathsFromFile()
+	if err != nil {
+		log.Println("read path history: failed to read path history\n", err)
+		return
+	}
 
-	for i := 0; i < len(recent_paths); i++ {
-		if recent_paths[i] == path {
-			recent_paths = append(recent_paths[:i], recent_paths[i+1:]...)
+	for _, path := range paths {
+		found := false
+
+		for i := range recent_paths {
+			if recent_paths[i].Path != path.Path {
+				continue
+			}
+
+			found = true
+			if path.TimesOpened > recent_paths[i].TimesOpened {
+				recent_paths[i].TimesOpened = path.TimesOpened
+			}
+			if path.LastAccess > recent_paths[i].LastAccess {
+				recent_paths[i].LastAccess = path.LastAccess
+			}
+			break
+		}
+
+		if !found {
+			recent_paths = append(recent_paths, path)
 		}
 	}
 
-	return recent_paths
+	SaveRecentPaths(recent_paths)
+}
+
+// Frecency ranks a path by how often and how recently it was opened, like
+// zoxide does: the open count weighted by the time since the last access.
+func Frecency(recent_path RecentPath, now time.Time) float64 {
+	last_access, err := time.Parse(time.RFC3339, recent_path.LastAccess)
+	if err != nil {
+		return float64(recent_path.TimesOpened) / 4
+	}
+
+	weight := 0.25
+	switch age := now.Sub(last_access); {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 0.5
+	}
+
+	return float64(recent_path.TimesOpened) * weight
 }
@@ -0,0 +1,177 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	file_utils "github.com/yur4uwe/cmd-project-manager/file_utils"
+)
+
+var ErrAlreadyArchived = errors.New("project is archived already")
+var ErrNotArchived = errors.New("project is not archived")
+
+var archive_dir = ".archives"
+
+// SetArchiveDir switches where ArchiveProject keeps compressed directories.
+func SetArchiveDir(dir string) {
+	archive_dir = dir
+}
+
+// ActiveProjects returns the projects that aren't archived.
+func ActiveProjects(projects []Project) []Project {
+	var active []Project
+	for _, p := range projects {
+		if p.Archived == "" {
+			active = append(active, p)
+		}
+	}
+
+	return active
+}
+
+// ArchivedProjects returns the archived projects.
+func ArchivedProjects(projects []Project) []Project {
+	var archived []Project
+	for _, p := range projects {
+		if p.Archived != "" {
+			archived = append(archived, p)
+		}
+	}
+
+	return archived
+}
+
+/*
+ArchiveProject hides a project from the default lists and unpins it.
+
+Parameters:
+- store: The project store.
+- id: The ID of the project.
+- compress: Whether to pack the directory into a tar.gz file in the archive directory and delete it. The directory is only deleted once the archive is complete and the project is saved.
+
+Returns:
+- Project: The archived project.
+- error: ErrAlreadyArchived, or an error if the archive or the project could not be written.
+*/
+func ArchiveProject(store ProjectStore, id string, compress bool) (Project, error) {
+	log.Println("Archive Project By ID")
+
+	archived, err := store.Get(id)
+	if err != nil {
+		return Project{}, err
+	}
+
+	if archived.Archived != "" {
+		return Project{}, fmt.Errorf("archive %s: %w", archived.Name, ErrAlreadyArchived)
+	}
+
+	if compress {
+		if err := os.MkdirAll(archive_dir, 0755); err != nil {
+			return Project{}, fmt.Errorf("create archive directory: %w", err)
+		}
+
+		archived.ArchiveFile = filepath.Join(archive_dir, fmt.Sprintf("%s-%s.tar.gz", filepath.Base(archived.Path), archived.ID))
+		if err := file_utils.CompressDir(archived.Path, archived.ArchiveFile); err != nil {
+			return Project{}, err
+		}
+	}
+
+	archived.Archived = time.Now().Format(time.RFC3339)
+	archived.Pinned = 0
+
+	if err := store.Put(archived); err != nil {
+		if compress {
+			os.Remove(archived.ArchiveFile)
+		}
+		return Project{}, err
+	}
+
+	if compress {
+		if err := os.RemoveAll(archived.Path); err != nil {
+			return archived, fmt.Errorf("archived to %s but could not delete the directory: %w", archived.ArchiveFile, err)
+		}
+	}
+
+	return archived, nil
+}
+
+/*
+RestoreProject brings an archived project back. A compressed directory is
+unpacked to its original path and the archive file deleted.
+
+Parameters:
+- store: The project store.
+- id: The ID of the project.
+
+Returns:
+- Project: The restored project.
+- error: ErrNotArchived, or an error if the archive file is outside the archive directory, the original path is taken or the archive could not be unpacked.
+*/
+func RestoreProject(store ProjectStore, id string) (Project, error) {
+	log.Println("Restore Project By ID")
+
+	restored, err := store.Get(id)
+	if err != nil {
+		return Project{}, err
+	}
+
+	if restored.Archived == "" {
+		return Project{}, fmt.Errorf("restore %s: %w", restored.Name, ErrNotArchived)
+	}
+
+	archive_file := restored.ArchiveFile
+	if archive_file != "" {
+		if !inArchiveDir(archive_file) {
+			return Project{}, fmt.Errorf("restore %s: archive file %s is not in the archive directory %s", restored.Name, archive_file, archive_dir)
+		}
+
+		if _, err := os.Stat(restored.Path); err == nil {
+			return Project{}, fmt.Errorf("restore %s: %s exists already", restored.Name, restored.Path)
+		}
+
+		if err := file_utils.ExtractArchive(archive_file, restored.Path); err != nil {
+			return Project{}, err
+		}
+	}
+
+	restored.Archived = ""
+	restored.ArchiveFile = ""
+
+	if err := store.Put(restored); err != nil {
+		return Project{}, err
+	}
+
+	if archive_file != "" {
+		if err := os.Remove(archive_file); err != nil {
+			log.Println("Error while deleting restored archive: ", err)
+		}
+	}
+
+	return restored, nil
+}
+
+// inArchiveDir reports whether path is a file inside the archive directory,
+// the only place RestoreProject unpacks and deletes archives from.
+func inArchiveDir(path string) bool {
+	dir, err := filepath.Abs(archive_dir)
+	if err != nil {
+		return false
+	}
+
+	file, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return false
+	}
+
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
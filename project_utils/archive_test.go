@@ -0,0 +1,39 @@
+package project_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+func TestRestoreRefusesArchiveOutsideArchiveDir(t *testing.T) {
+	dir := t.TempDir()
+	project.SetArchiveDir(filepath.Join(dir, "archives"))
+
+	victim := filepath.Join(dir, "victim.txt")
+	if err := os.WriteFile(victim, []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := project.Project{
+		ID:          "01HZAAAAAAAAAAAAAAAAAAAAAA",
+		Name:        "alpha",
+		Path:        filepath.Join(dir, "alpha"),
+		Archived:    "2024-03-04T05:06:07Z",
+		ArchiveFile: filepath.Join(dir, "archives", "..", "victim.txt"),
+	}
+	store := project.NewMemoryStore(p)
+
+	if _, err := project.RestoreProject(store, p.ID); err == nil {
+		t.Fatal("restored from a file outside the archive directory")
+	}
+
+	if data, err := os.ReadFile(victim); err != nil || string(data) != "keep me" {
+		t.Fatalf("file outside the archive directory is %q, %v", data, err)
+	}
+	if got, _ := store.Get(p.ID); got.Archived == "" {
+		t.Fatal("project was marked restored")
+	}
+}
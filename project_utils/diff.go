@@ -0,0 +1,77 @@
+package project
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	CHANGE_ADDED   = "added"
+	CHANGE_REMOVED = "removed"
+	CHANGE_CHANGED = "changed"
+)
+
+// ProjectChange is the difference of one project between two registries.
+type ProjectChange struct {
+	Change string
+	Old    *Project
+	New    *Project
+	Fields []string
+}
+
+func (c ProjectChange) String() string {
+	switch c.Change {
+	case CHANGE_ADDED:
+		return fmt.Sprintf("+ %s (%s)", c.New.Name, c.New.Path)
+	case CHANGE_REMOVED:
+		return fmt.Sprintf("- %s (%s)", c.Old.Name, c.Old.Path)
+	}
+
+	return fmt.Sprintf("~ %s: %s", c.New.Name, strings.Join(c.Fields, ", "))
+}
+
+// DiffProjects compares two registries by project ID. The changes are in
+// the order of before, followed by the projects only after has.
+func DiffProjects(before, after []Project) []ProjectChange {
+	var changes []ProjectChange
+
+	before_by_id := indexProjects(before)
+	after_by_id := indexProjects(after)
+
+	for i := range before {
+		updated, ok := after_by_id[before[i].ID]
+		if !ok {
+			changes = append(changes, ProjectChange{Change: CHANGE_REMOVED, Old: &before[i]})
+			continue
+		}
+
+		if fields := changedFields(before[i], updated); len(fields) > 0 {
+			changes = append(changes, ProjectChange{Change: CHANGE_CHANGED, Old: &before[i], New: &updated, Fields: fields})
+		}
+	}
+
+	for i := range after {
+		if _, ok := before_by_id[after[i].ID]; !ok {
+			changes = append(changes, ProjectChange{Change: CHANGE_ADDED, New: &after[i]})
+		}
+	}
+
+	return changes
+}
+
+// changedFields lists the names of the Project fields that differ.
+func changedFields(a, b Project) []string {
+	var fields []string
+
+	a_value := reflect.ValueOf(a)
+	b_value := reflect.ValueOf(b)
+
+	for i := 0; i < a_value.NumField(); i++ {
+		if !reflect.DeepEqual(a_value.Field(i).Interface(), b_value.Field(i).Interface()) {
+			fields = append(fields, a_value.Type().Field(i).Name)
+		}
+	}
+
+	return fields
+}
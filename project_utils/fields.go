@@ -0,0 +1,405 @@
+package project
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The types a custom field can have.
+const (
+	FIELD_STRING = "string"
+	FIELD_DATE   = "date"
+	FIELD_ENUM   = "enum"
+	FIELD_URL    = "url"
+)
+
+// DATE_LAYOUT is how date fields are stored, so they sort as strings.
+const DATE_LAYOUT = "2006-01-02"
+
+// FieldDef describes a custom field projects can carry in Fields.
+type FieldDef struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Values are the allowed values of an enum field.
+	Values []string `json:"values,omitempty"`
+}
+
+var field_schema []FieldDef
+
+// SetFieldSchema switches the custom fields that are known. It is checked
+// with CheckFieldSchema first.
+func SetFieldSchema(defs []FieldDef) {
+	field_schema = defs
+}
+
+// FieldSchema returns the known custom fields, in the configured order.
+func FieldSchema() []FieldDef {
+	return field_schema
+}
+
+// CheckFieldSchema reports the first field definition that is unusable.
+func CheckFieldSchema(defs []FieldDef) error {
+	seen := make(map[string]bool)
+
+	for _, def := range defs {
+		name := strings.ToLower(strings.TrimSpace(def.Name))
+		if name == "" {
+			return fmt.Errorf("custom field without a name")
+		}
+		if seen[name] {
+			return fmt.Errorf("custom field %q is defined twice", def.Name)
+		}
+		seen[name] = true
+
+		switch name {
+		case SORT_NAME, SORT_PATH, SORT_CREATED, SORT_OPENED:
+			return fmt.Errorf("custom field can't be called %q, that is a built-in sort key", def.Name)
+		}
+
+		switch def.Type {
+		case FIELD_STRING, FIELD_DATE, FIELD_URL:
+		case FIELD_ENUM:
+			if len(def.Values) == 0 {
+				return fmt.Errorf("enum field %q has no values", def.Name)
+			}
+		default:
+			return fmt.Errorf("custom field %q has unknown type %q, expected %s, %s, %s or %s",
+				def.Name, def.Type, FIELD_STRING, FIELD_DATE, FIELD_ENUM, FIELD_URL)
+		}
+	}
+
+	return nil
+}
+
+// FindField looks up a custom field by name, ignoring case.
+func FindField(name string) (FieldDef, bool) {
+	for _, def := range field_schema {
+		if strings.EqualFold(def.Name, strings.TrimSpace(name)) {
+			return def, true
+		}
+	}
+
+	return FieldDef{}, false
+}
+
+// Hint describes what values the field takes, for prompts.
+func (def FieldDef) Hint() string {
+	switch def.Type {
+	case FIELD_DATE:
+		return "date, YYYY-MM-DD"
+	case FIELD_ENUM:
+		return "one of " + strings.Join(def.Values, ", ")
+	case FIELD_URL:
+		return "URL"
+	}
+
+	return "text"
+}
+
+/*
+Check validates a value for the field.
+
+Returns:
+- string: The value as it is stored. Dates are in DATE_LAYOUT and enum values spelled like in the definition.
+- error: An error saying what is wrong with the value.
+*/
+func (def FieldDef) Check(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch def.Type {
+	case FIELD_DATE:
+		date, err := time.Parse(DATE_LAYOUT, value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %q is not a date like 2024-12-31", def.Name, value)
+		}
+		return date.Format(DATE_LAYOUT), nil
+	case FIELD_ENUM:
+		for _, allowed := range def.Values {
+			if strings.EqualFold(allowed, value) {
+				return allowed, nil
+			}
+		}
+		return "", fmt.Errorf("%s: %q is not one of %s", def.Name, value, strings.Join(def.Values, ", "))
+	case FIELD_URL:
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return "", fmt.Errorf("%s: %q is not a URL like https://example.com", def.Name, value)
+		}
+		return value, nil
+	}
+
+	return value, nil
+}
+
+/*
+SetField validates value and stores it in the custom field of the project.
+
+Parameters:
+- p: The project to change.
+- name: The name of a field in the schema.
+- value: The new value. An empty value removes the field.
+
+Returns:
+- error: An error if the field is unknown or the value invalid.
+*/
+func SetField(p *Project, name, value string) error {
+	def, ok := FindField(name)
+	if !ok {
+		return unknownFieldError(name)
+	}
+
+	if strings.TrimSpace(value) == "" {
+		delete(p.Fields, def.Name)
+		if len(p.Fields) == 0 {
+			p.Fields = nil
+		}
+		return nil
+	}
+
+	checked, err := def.Check(value)
+	if err != nil {
+		return err
+	}
+
+	if p.Fields == nil {
+		p.Fields = make(map[string]string)
+	}
+	p.Fields[def.Name] = checked
+
+	return nil
+}
+
+// ApplyFields sets every field of fields on the project with SetField, in
+// the order of the schema so the first invalid one is reported the same
+// way every time.
+func ApplyFields(p *Project, fields map[string]string) error {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := SetField(p, name, fields[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseFieldAssignment splits "name=value" as given on the command line.
+func ParseFieldAssignment(assignment string) (string, string, error) {
+	name, value, ok := strings.Cut(assignment, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return "", "", fmt.Errorf("field %q is not in the form name=value", assignment)
+	}
+
+	return strings.TrimSpace(name), value, nil
+}
+
+func unknownFieldError(name string) error {
+	if len(field_schema) == 0 {
+		return fmt.Errorf("unknown field %q, no custom fields are configured", name)
+	}
+
+	names := make([]string, len(field_schema))
+	for i, def := range field_schema {
+		names[i] = def.Name
+	}
+
+	return fmt.Errorf("unknown field %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// PrintFields prints the custom fields of the project, those of the schema
+// first in its order, then any others it still carries.
+func PrintFields(p Project) string {
+	var fields string
+	printed := make(map[string]bool)
+
+	for _, def := range field_schema {
+		if value, ok := p.Fields[def.Name]; ok {
+			fields += fmt.Sprintf("%s: %s\n", def.Name, value)
+			printed[def.Name] = true
+		}
+	}
+
+	var others []string
+	for name := range p.Fields {
+		if !printed[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+
+	for _, name := range others {
+		fields += fmt.Sprintf("%s: %s\n", name, p.Fields[name])
+	}
+
+	return fields
+}
+
+// FieldFilter keeps the projects whose field compares to Value with Op.
+type FieldFilter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// The operators of a FieldFilter, longest first so they win over the
+// shorter ones they start with.
+var filterOps = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+/*
+ParseFieldFilter parses a filter like "client=acme" or "deadline<2025-01-01".
+Values are compared ignoring case, < and > compare them as text, which
+orders dates correctly. "name=" matches projects without the field.
+
+Returns:
+- FieldFilter: The filter, with the field spelled like in the schema.
+- error: An error if the filter is malformed or the field unknown.
+*/
+func ParseFieldFilter(filter string) (FieldFilter, error) {
+	// The value may contain operators too, so the first one splits
+	at, op := -1, ""
+	for _, candidate := range filterOps {
+		if i := strings.Index(filter, candidate); i >= 0 && (at < 0 || i < at) {
+			at, op = i, candidate
+		}
+	}
+
+	if at < 0 {
+		return FieldFilter{}, fmt.Errorf("filter %q is not in the form field=value, with =, !=, <, <=, > or >=", filter)
+	}
+
+	name, value := filter[:at], filter[at+len(op):]
+
+	def, found := FindField(name)
+	if !found {
+		return FieldFilter{}, unknownFieldError(strings.TrimSpace(name))
+	}
+
+	return FieldFilter{Field: def.Name, Op: op, Value: strings.TrimSpace(value)}, nil
+}
+
+// Matches reports whether the project passes the filter.
+func (f FieldFilter) Matches(p Project) bool {
+	value := strings.ToLower(p.Fields[f.Field])
+	want := strings.ToLower(f.Value)
+
+	switch f.Op {
+	case "=":
+		return value == want
+	case "!=":
+		return value != want
+	}
+
+	// A missing value doesn't compare
+	if value == "" {
+		return false
+	}
+
+	switch f.Op {
+	case "<":
+		return value < want
+	case "<=":
+		return value <= want
+	case ">":
+		return value > want
+	case ">=":
+		return value >= want
+	}
+
+	return false
+}
+
+// FilterByFields returns the projects passing every filter.
+func FilterByFields(projects []Project, filters []FieldFilter) []Project {
+	if len(filters) == 0 {
+		return projects
+	}
+
+	var filtered []Project
+	for _, p := range projects {
+		passes := true
+		for _, filter := range filters {
+			if !filter.Matches(p) {
+				passes = false
+				break
+			}
+		}
+		if passes {
+			filtered = append(filtered, p)
+		}
+	}
+
+	return filtered
+}
+
+// The sort keys besides the custom fields.
+const (
+	SORT_NAME    = "name"
+	SORT_PATH    = "path"
+	SORT_CREATED = "created"
+	// SORT_OPENED sorts listings by how often the project directory was
+	// opened, which only QueryProjects knows, so SortProjects doesn't take it.
+	SORT_OPENED = "opened"
+)
+
+// SortKeys returns every key SortProjects accepts.
+func SortKeys() []string {
+	keys := []string{SORT_NAME, SORT_PATH, SORT_CREATED}
+	for _, def := range field_schema {
+		keys = append(keys, def.Name)
+	}
+
+	return keys
+}
+
+/*
+SortProjects sorts the projects in place.
+
+Parameters:
+- projects: The projects to sort.
+- key: SORT_NAME, SORT_PATH, SORT_CREATED or the name of a custom field, with a leading "-" to sort descending. Projects without the field come last either way.
+
+Returns:
+- error: An error if the key is unknown.
+*/
+func SortProjects(projects []Project, key string) error {
+	descending := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+
+	var value func(p Project) string
+
+	switch strings.ToLower(key) {
+	case SORT_NAME:
+		value = func(p Project) string { return strings.ToLower(p.Name) }
+	case SORT_PATH:
+		value = func(p Project) string { return p.Path }
+	case SORT_CREATED:
+		value = func(p Project) string { return p.TimeStamp }
+	default:
+		def, ok := FindField(key)
+		if !ok {
+			return fmt.Errorf("unknown sort key %q, expected one of %s", key, strings.Join(SortKeys(), ", "))
+		}
+		value = func(p Project) string { return strings.ToLower(p.Fields[def.Name]) }
+	}
+
+	sort.SliceStable(projects, func(i, j int) bool {
+		a, b := value(projects[i]), value(projects[j])
+		if a == "" || b == "" {
+			return b == "" && a != ""
+		}
+		if descending {
+			return a > b
+		}
+		return a < b
+	})
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package project_test
+
+import (
+	"testing"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+func TestCheckFieldSchema(t *testing.T) {
+	cases := []struct {
+		name string
+		defs []project.FieldDef
+		ok   bool
+	}{
+		{"valid", []project.FieldDef{{Name: "client", Type: project.FIELD_STRING}, {Name: "due", Type: project.FIELD_DATE}}, true},
+		{"no name", []project.FieldDef{{Type: project.FIELD_STRING}}, false},
+		{"twice", []project.FieldDef{{Name: "client", Type: project.FIELD_STRING}, {Name: "Client", Type: project.FIELD_URL}}, false},
+		{"unknown type", []project.FieldDef{{Name: "client", Type: "number"}}, false},
+		{"enum without values", []project.FieldDef{{Name: "status", Type: project.FIELD_ENUM}}, false},
+		{"sort key name", []project.FieldDef{{Name: "name", Type: project.FIELD_STRING}}, false},
+		{"sort key path", []project.FieldDef{{Name: "Path", Type: project.FIELD_STRING}}, false},
+		{"sort key created", []project.FieldDef{{Name: "created", Type: project.FIELD_DATE}}, false},
+		{"sort key opened", []project.FieldDef{{Name: " opened ", Type: project.FIELD_STRING}}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := project.CheckFieldSchema(c.defs)
+			if (err == nil) != c.ok {
+				t.Fatalf("CheckFieldSchema() error = %v, want ok %v", err, c.ok)
+			}
+		})
+	}
+}
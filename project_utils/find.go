@@ -0,0 +1,55 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrAmbiguousProject = errors.New("more than one project matches")
+
+// MIN_ID_PREFIX is how many characters of an ID are needed to refer to a
+// project by a prefix of it.
+const MIN_ID_PREFIX = 4
+
+/*
+FindProject finds the project a user refers to on the command line.
+
+Parameters:
+- projects: The registry.
+- ref: A full ID, a name (ignoring case) or a prefix of an ID of at least MIN_ID_PREFIX characters, tried in that order.
+
+Returns:
+- Project: The project ref refers to.
+- error: ErrProjectNotFound if nothing matches, ErrAmbiguousProject if an ID prefix matches several projects.
+*/
+func FindProject(projects []Project, ref string) (Project, error) {
+	for _, p := range projects {
+		if p.ID == ref {
+			return p, nil
+		}
+	}
+
+	for _, p := range projects {
+		if strings.EqualFold(p.Name, ref) {
+			return p, nil
+		}
+	}
+
+	if len(ref) >= MIN_ID_PREFIX {
+		var matches []Project
+		for _, p := range projects {
+			if strings.HasPrefix(strings.ToUpper(p.ID), strings.ToUpper(ref)) {
+				matches = append(matches, p)
+			}
+		}
+
+		if len(matches) == 1 {
+			return matches[0], nil
+		} else if len(matches) > 1 {
+			return Project{}, fmt.Errorf("%q: %w:\n%s", ref, ErrAmbiguousProject, PrintCompressedProjectsSlice(matches))
+		}
+	}
+
+	return Project{}, fmt.Errorf("%q: %w", ref, ErrProjectNotFound)
+}
@@ -0,0 +1,74 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// DEFAULT_LINE_FORMAT is the template of FormatProjects when none is given.
+const DEFAULT_LINE_FORMAT = `{{.ID}}\t{{.Name}}\t{{.Path}}`
+
+// The escapes a line format may use, since shells pass them on literally.
+var formatEscapes = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n", `\0`, "\x00")
+
+var formatFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+	"join": strings.Join,
+}
+
+/*
+FormatProjects writes one entry per project, rendered by a text/template
+over the Project struct.
+
+Parameters:
+- w: Where to write to.
+- projects: The projects to write.
+- format: The template, DEFAULT_LINE_FORMAT if empty. \t, \n, \0 and \\ are unescaped first. Besides the builtins it can use json and join.
+- terminator: What ends every entry, like "\n" or "\x00".
+
+Returns:
+- error: An error if the template doesn't parse or fails on a project.
+*/
+func FormatProjects(w io.Writer, projects []Project, format, terminator string) error {
+	if format == "" {
+		format = DEFAULT_LINE_FORMAT
+	}
+
+	tmpl, err := template.New("format").Funcs(formatFuncs).Option("missingkey=error").Parse(formatEscapes.Replace(format))
+	if err != nil {
+		return fmt.Errorf("parse format: %w", err)
+	}
+
+	for _, project := range projects {
+		if err := tmpl.Execute(w, project); err != nil {
+			return fmt.Errorf("format project %s: %w", project.Name, err)
+		}
+		if _, err := io.WriteString(w, terminator); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteProjectsJSON writes projects as an indented JSON array, with the
+// same field names as the projects file.
+func WriteProjectsJSON(w io.Writer, projects []Project) error {
+	if projects == nil {
+		projects = []Project{}
+	}
+
+	encoded, err := json.MarshalIndent(projects, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode projects: %w", err)
+	}
+
+	_, err = w.Write(append(encoded, '\n'))
+	return err
+}
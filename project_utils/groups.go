@@ -0,0 +1,462 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	file_utils "github.com/yur4uwe/cmd-project-manager/file_utils"
+)
+
+var ErrGroupNotFound = errors.New("group not found")
+
+// Group is a named set of projects that are opened together.
+type Group struct {
+	Name string `json:"Name"`
+	// Projects are the IDs of the members, in the order they were added.
+	Projects []string `json:"Projects"`
+}
+
+var groups_file = ".groups.json"
+var workspace_dir = ".workspaces"
+var editor = "code"
+
+// SetGroupsFile switches where the groups are kept.
+func SetGroupsFile(path string) {
+	groups_file = path
+}
+
+// SetWorkspaceDir switches where OpenGroup writes .code-workspace files.
+func SetWorkspaceDir(dir string) {
+	workspace_dir = dir
+}
+
+// SetEditor switches the command projects and groups are opened with. It
+// may carry arguments, like "code --new-window".
+func SetEditor(command string) {
+	if strings.TrimSpace(command) != "" {
+		editor = command
+	}
+}
+
+// LoadGroups reads the groups, sorted by name. A missing file means none.
+func LoadGroups() ([]Group, error) {
+	file, err := os.ReadFile(groups_file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read groups file: %w", err)
+	}
+
+	var groups []Group
+	if err := json.Unmarshal(file, &groups); err != nil {
+		return nil, fmt.Errorf("parse groups file %s: %w", groups_file, err)
+	}
+
+	// The file may have been edited by hand
+	sortGroups(groups)
+
+	return groups, nil
+}
+
+// SaveGroups writes the groups, sorted by name.
+func SaveGroups(groups []Group) error {
+	sortGroups(groups)
+
+	groups_json, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal groups: %w", err)
+	}
+
+	if err := file_utils.WriteFileAtomic(groups_file, groups_json, 0644); err != nil {
+		return fmt.Errorf("write groups file: %w", err)
+	}
+
+	return nil
+}
+
+func sortGroups(groups []Group) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Name) < strings.ToLower(groups[j].Name)
+	})
+}
+
+// FindGroup returns the index of the group called name, ignoring case, or
+// an error wrapping ErrGroupNotFound.
+func FindGroup(groups []Group, name string) (int, error) {
+	for i, g := range groups {
+		if strings.EqualFold(g.Name, strings.TrimSpace(name)) {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("%q: %w", name, ErrGroupNotFound)
+}
+
+// CheckGroupName fails for names that are empty, taken, or can't be a file
+// name, which the workspace file of the group is called after.
+func CheckGroupName(groups []Group, name string) error {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return fmt.Errorf("group name is empty")
+	}
+	if strings.ContainsAny(name, `<>:"/\|?*`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("group name %q can't contain any of <>:\"/\\|?* or start with a dot", name)
+	}
+	if _, err := FindGroup(groups, name); err == nil {
+		return fmt.Errorf("a group called %q already exists", name)
+	}
+
+	return nil
+}
+
+// CreateGroup adds a group with the projects of ids as members.
+func CreateGroup(name string, ids []string) (Group, error) {
+	log.Println("Create Group")
+
+	groups, err := LoadGroups()
+	if err != nil {
+		return Group{}, err
+	}
+
+	if err := CheckGroupName(groups, name); err != nil {
+		return Group{}, err
+	}
+
+	created := Group{Name: strings.TrimSpace(name), Projects: []string{}}
+	addMembers(&created, ids)
+
+	return created, SaveGroups(append(groups, created))
+}
+
+// DeleteGroup removes a group, its projects are left alone.
+func DeleteGroup(name string) error {
+	log.Println("Delete Group")
+
+	groups, err := LoadGroups()
+	if err != nil {
+		return err
+	}
+
+	i, err := FindGroup(groups, name)
+	if err != nil {
+		return err
+	}
+
+	return SaveGroups(append(groups[:i], groups[i+1:]...))
+}
+
+// RenameGroup gives a group a new name.
+func RenameGroup(name, new_name string) error {
+	log.Println("Rename Group")
+
+	groups, err := LoadGroups()
+	if err != nil {
+		return err
+	}
+
+	i, err := FindGroup(groups, name)
+	if err != nil {
+		return err
+	}
+
+	others := append(append([]Group{}, groups[:i]...), groups[i+1:]...)
+	if err := CheckGroupName(others, new_name); err != nil {
+		return err
+	}
+
+	groups[i].Name = strings.TrimSpace(new_name)
+
+	return SaveGroups(groups)
+}
+
+/*
+SetGroupMembers changes the members of a group.
+
+Parameters:
+- name: The name of the group.
+- add: The IDs of projects to add. Members already in the group keep their place.
+- remove: The IDs of projects to remove.
+
+Returns:
+- Group: The changed group.
+- error: ErrGroupNotFound, or an error if the groups could not be read or saved.
+*/
+func SetGroupMembers(name string, add, remove []string) (Group, error) {
+	log.Println("Set Group Members")
+
+	groups, err := LoadGroups()
+	if err != nil {
+		return Group{}, err
+	}
+
+	i, err := FindGroup(groups, name)
+	if err != nil {
+		return Group{}, err
+	}
+
+	removeMembers(&groups[i], remove)
+	addMembers(&groups[i], add)
+
+	return groups[i], SaveGroups(groups)
+}
+
+// RemoveFromGroups drops a removed project from every group it is in.
+func RemoveFromGroups(id string) error {
+	groups, err := LoadGroups()
+	if err != nil || len(groups) == 0 {
+		return err
+	}
+
+	changed := false
+	for i := range groups {
+		before := len(groups[i].Projects)
+		removeMembers(&groups[i], []string{id})
+		changed = changed || len(groups[i].Projects) != before
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return SaveGroups(groups)
+}
+
+// groupsOf returns the names of the groups the project with ID id is in.
+func groupsOf(id string) ([]string, error) {
+	groups, err := LoadGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, g := range groups {
+		if g.HasMember(id) {
+			names = append(names, g.Name)
+		}
+	}
+
+	return names, nil
+}
+
+// addToGroups puts a project back into the named groups, after their other
+// members. Groups that are gone since are left out.
+func addToGroups(id string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	groups, err := LoadGroups()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if i, err := FindGroup(groups, name); err == nil {
+			addMembers(&groups[i], []string{id})
+		}
+	}
+
+	return SaveGroups(groups)
+}
+
+func addMembers(g *Group, ids []string) {
+	for _, id := range ids {
+		if !g.HasMember(id) {
+			g.Projects = append(g.Projects, id)
+		}
+	}
+}
+
+func removeMembers(g *Group, ids []string) {
+	kept := g.Projects[:0]
+	for _, member := range g.Projects {
+		removed := false
+		for _, id := range ids {
+			if member == id {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			kept = append(kept, member)
+		}
+	}
+	g.Projects = kept
+}
+
+// HasMember reports whether the project with ID id is in the group.
+func (g Group) HasMember(id string) bool {
+	for _, member := range g.Projects {
+		if member == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+GroupMembers looks up the members of a group among projects.
+
+Returns:
+- []Project: The members in the order of the group.
+- []string: The IDs of members that aren't in projects anymore.
+*/
+func GroupMembers(projects []Project, g Group) ([]Project, []string) {
+	by_id := make(map[string]Project, len(projects))
+	for _, p := range projects {
+		by_id[p.ID] = p
+	}
+
+	var members []Project
+	var missing []string
+	for _, id := range g.Projects {
+		if p, ok := by_id[id]; ok {
+			members = append(members, p)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	return members, missing
+}
+
+// PrintGroupInfo prints a group and its members.
+func PrintGroupInfo(g Group, projects []Project) string {
+	members, missing := GroupMembers(projects, g)
+
+	group_info := fmt.Sprintf("Group: %s\nProjects: %d\n", g.Name, len(members))
+	for _, p := range members {
+		group_info += fmt.Sprintf("  %s (%s)", p.Name, p.Path)
+		if p.Archived != "" {
+			group_info += " [archived]"
+		}
+		group_info += "\n"
+	}
+	if len(missing) > 0 {
+		group_info += fmt.Sprintf("Missing: %s\n", strings.Join(missing, ", "))
+	}
+
+	return group_info
+}
+
+// openableMembers returns the members that have a directory to open.
+// Archived members are skipped.
+func openableMembers(projects []Project, g Group) ([]Project, error) {
+	members, _ := GroupMembers(projects, g)
+	members = ActiveProjects(members)
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("group %s has no projects to open", g.Name)
+	}
+
+	return members, nil
+}
+
+/*
+WriteWorkspace writes a VS Code .code-workspace file with a folder for every
+member of the group that isn't archived. The file is called after the group
+and written again every time, so it follows the members.
+
+Returns:
+- string: The path of the workspace file.
+- error: An error if the group has nothing to open or the file could not be written.
+*/
+func WriteWorkspace(projects []Project, g Group) (string, error) {
+	members, err := openableMembers(projects, g)
+	if err != nil {
+		return "", err
+	}
+
+	type folder struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	}
+
+	workspace := struct {
+		Folders  []folder               `json:"folders"`
+		Settings map[string]interface{} `json:"settings"`
+	}{Settings: map[string]interface{}{}}
+
+	for _, p := range members {
+		workspace.Folders = append(workspace.Folders, folder{Name: p.Name, Path: filepath.FromSlash(p.Path)})
+	}
+
+	workspace_json, err := json.MarshalIndent(workspace, "", "\t")
+	if err != nil {
+		return "", fmt.Errorf("marshal workspace: %w", err)
+	}
+
+	if err := os.MkdirAll(workspace_dir, 0755); err != nil {
+		return "", fmt.Errorf("create workspace directory: %w", err)
+	}
+
+	path := filepath.Join(workspace_dir, g.Name+".code-workspace")
+	if err := file_utils.WriteFileAtomic(path, workspace_json, 0644); err != nil {
+		return "", fmt.Errorf("write workspace file: %w", err)
+	}
+
+	return path, nil
+}
+
+/*
+OpenGroup opens every member of the group that isn't archived in the editor.
+
+Parameters:
+- projects: Every project, to look the members up in.
+- g: The group.
+- workspace: Whether to write a .code-workspace file with all members and open that instead of each member on its own.
+
+Returns:
+- error: An error if nothing could be opened, or the editor failed for a member. The other members are still opened.
+*/
+func OpenGroup(projects []Project, g Group, workspace bool) error {
+	log.Println("Open Group")
+
+	if workspace {
+		path, err := WriteWorkspace(projects, g)
+		if err != nil {
+			return err
+		}
+		return openInEditor(path)
+	}
+
+	members, err := openableMembers(projects, g)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, p := range members {
+		if err := openInEditor(p.Path); err != nil {
+			failed = append(failed, p.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not open %s in %s", strings.Join(failed, ", "), editor)
+	}
+
+	return nil
+}
+
+// openInEditor runs the editor on path.
+func openInEditor(path string) error {
+	args := append(strings.Fields(editor), path)
+	cmd := exec.Command(args[0], args[1:]...)
+
+	if err := cmd.Run(); err != nil {
+		log.Printf("Error while opening %s in %s: %v\n", path, editor, err)
+		return fmt.Errorf("open %s: %w", editor, err)
+	}
+
+	return nil
+}
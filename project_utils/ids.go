@@ -0,0 +1,154 @@
+package project
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const crockford_alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	id_mutex     sync.Mutex
+	last_id_time uint64
+	last_entropy [10]byte
+)
+
+/*
+NewProjectID returns a new ULID style project ID: 26 Crockford base32
+characters, a 48 bit millisecond timestamp followed by 80 random bits.
+
+IDs sort by creation time, and IDs created within the same millisecond
+increment the random part so they stay unique and ordered.
+*/
+func NewProjectID() string {
+	id_mutex.Lock()
+	defer id_mutex.Unlock()
+
+	now := uint64(time.Now().UnixMilli())
+
+	if now <= last_id_time {
+		now = last_id_time
+		incrementEntropy(&last_entropy)
+	} else {
+		last_id_time = now
+		if _, err := rand.Read(last_entropy[:]); err != nil {
+			log.Fatal("Error while generating project ID: ", err)
+		}
+	}
+
+	var id [16]byte
+	for i := 0; i < 6; i++ {
+		id[i] = byte(now >> (40 - 8*i))
+	}
+	copy(id[6:], last_entropy[:])
+
+	return encodeCrockford(id)
+}
+
+/*
+legacyProjectID derives the permanent ID of a project from a registry that
+had none, from its place in the list and its fields. Decoding the same old
+registry, like a snapshot taken before the migration, gives the same IDs
+every time, so they match the migrated registry.
+
+The timestamp part comes from TimeStamp when it parses, so the IDs still
+sort by creation time.
+*/
+func legacyProjectID(p Project, index int) string {
+	var millis uint64
+	if created, err := time.Parse(time.RFC3339, p.TimeStamp); err == nil && created.UnixMilli() > 0 {
+		millis = uint64(created.UnixMilli())
+	}
+
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s\x00%s\x00%s", index, p.Name, p.Path, p.TimeStamp)))
+
+	var id [16]byte
+	for i := 0; i < 6; i++ {
+		id[i] = byte(millis >> (40 - 8*i))
+	}
+	copy(id[6:], hash[:10])
+
+	return encodeCrockford(id)
+}
+
+func incrementEntropy(entropy *[10]byte) {
+	for i := len(entropy) - 1; i >= 0; i-- {
+		entropy[i]++
+		if entropy[i] != 0 {
+			return
+		}
+	}
+}
+
+// encodeCrockford encodes 128 bits as 26 base32 characters, most
+// significant bits first, the way ULIDs are written.
+func encodeCrockford(id [16]byte) string {
+	encoded := make([]byte, 26)
+
+	for i := 25; i >= 0; i-- {
+		bit := (25 - i) * 5
+		var value byte
+
+		for b := 0; b < 5; b++ {
+			pos := bit + b
+			if pos >= 128 {
+				break
+			}
+			byte_index := 15 - pos/8
+			if id[byte_index]&(1<<(pos%8)) != 0 {
+				value |= 1 << b
+			}
+		}
+
+		encoded[i] = crockford_alphabet[value]
+	}
+
+	return string(encoded)
+}
+
+// UnmarshalJSON accepts the legacy numeric IDs from before stable IDs.
+// Those are dropped so AssignMissingIDs gives the project a permanent one.
+func (p *Project) UnmarshalJSON(data []byte) error {
+	type project_fields Project
+
+	var raw struct {
+		project_fields
+		ID json.RawMessage `json:"ID"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*p = Project(raw.project_fields)
+	p.ID = ""
+
+	var id string
+	if err := json.Unmarshal(raw.ID, &id); err == nil {
+		p.ID = id
+	}
+
+	return nil
+}
+
+// AssignMissingIDs gives every project without an ID, or with an ID that
+// is already taken, a new permanent one. Reports whether anything changed.
+func AssignMissingIDs(projects []Project) bool {
+	changed := false
+	seen := make(map[string]bool, len(projects))
+
+	for i := range projects {
+		if projects[i].ID == "" || seen[projects[i].ID] {
+			projects[i].ID = NewProjectID()
+			changed = true
+		}
+		seen[projects[i].ID] = true
+	}
+
+	return changed
+}
@@ -0,0 +1,337 @@
+package project
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	JOURNAL_ADD     = "add"
+	JOURNAL_UPDATE  = "update"
+	JOURNAL_REMOVE  = "remove"
+	JOURNAL_REPLACE = "replace"
+	JOURNAL_UNDO    = "undo"
+	JOURNAL_REDO    = "redo"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+	// ErrArchiveNotUndoable is returned for changes that packed a project
+	// directory into an archive file or unpacked it, since putting the
+	// record back would not move the directory.
+	ErrArchiveNotUndoable = errors.New("compressing or unpacking a project can't be undone, archive or restore it instead")
+)
+
+// JournalEntry is one line of the operation journal. Mutations carry the
+// project before and after the change, nil meaning it did not exist.
+// Removals also carry the groups the project was in. Registry replacements
+// carry the whole registry instead. Undo and redo entries only point at the
+// mutation they reverted or reapplied.
+type JournalEntry struct {
+	Seq       int       `json:"seq"`
+	Time      string    `json:"time"`
+	Op        string    `json:"op"`
+	Target    int       `json:"target,omitempty"`
+	Before    *Project  `json:"before,omitempty"`
+	After     *Project  `json:"after,omitempty"`
+	Groups    []string  `json:"groups,omitempty"`
+	AllBefore []Project `json:"all_before,omitempty"`
+	AllAfter  []Project `json:"all_after,omitempty"`
+}
+
+func (e JournalEntry) String() string {
+	switch e.Op {
+	case JOURNAL_ADD:
+		return fmt.Sprintf("add project %q", e.After.Name)
+	case JOURNAL_UPDATE:
+		return fmt.Sprintf("update project %q", e.After.Name)
+	case JOURNAL_REMOVE:
+		return fmt.Sprintf("remove project %q", e.Before.Name)
+	case JOURNAL_REPLACE:
+		return "replace the whole registry"
+	}
+
+	return e.Op
+}
+
+/*
+JournaledStore wraps a ProjectStore and appends every mutation to an
+operation journal file, which makes them undoable across restarts.
+
+The journal is append-only. Undo and redo add entries of their own, and
+the undo and redo stacks are rebuilt by replaying the file.
+*/
+type JournaledStore struct {
+	ProjectStore
+	path     string
+	next_seq int
+}
+
+func NewJournaledStore(store ProjectStore, journal_path string) *JournaledStore {
+	return &JournaledStore{ProjectStore: store, path: journal_path}
+}
+
+func (s *JournaledStore) Put(project Project) error {
+	if project.ID == "" {
+		project.ID = NewProjectID()
+	}
+
+	before, err := s.getIfExists(project.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ProjectStore.Put(project); err != nil {
+		return err
+	}
+
+	op := JOURNAL_UPDATE
+	if before == nil {
+		op = JOURNAL_ADD
+	}
+
+	return s.append(JournalEntry{Op: op, Before: before, After: &project})
+}
+
+func (s *JournaledStore) Delete(id string) error {
+	before, err := s.getIfExists(id)
+	if err != nil {
+		return err
+	}
+
+	// Taken before RemoveProject drops the project from its groups, so
+	// undo can put it back in them
+	groups, err := groupsOf(id)
+	if err != nil {
+		log.Println("Error while reading the groups of a removed project: ", err)
+	}
+
+	if err := s.ProjectStore.Delete(id); err != nil {
+		return err
+	}
+
+	return s.append(JournalEntry{Op: JOURNAL_REMOVE, Before: before, Groups: groups})
+}
+
+func (s *JournaledStore) Save(projects []Project) error {
+	before, err := s.ProjectStore.List()
+	if err != nil {
+		return err
+	}
+
+	if err := s.ProjectStore.Save(projects); err != nil {
+		return err
+	}
+
+	after, err := s.ProjectStore.List()
+	if err != nil {
+		return err
+	}
+
+	return s.append(JournalEntry{Op: JOURNAL_REPLACE, AllBefore: before, AllAfter: after})
+}
+
+// TakeConflicts passes through the conflicts of the wrapped store.
+func (s *JournaledStore) TakeConflicts() []MergeConflict {
+	if reporter, ok := s.ProjectStore.(ConflictReporter); ok {
+		return reporter.TakeConflicts()
+	}
+
+	return nil
+}
+
+// Undo reverts the most recent mutation that is not undone yet.
+func (s *JournaledStore) Undo() (JournalEntry, error) {
+	entries, err := s.read()
+	if err != nil {
+		return JournalEntry{}, err
+	}
+
+	undo_stack, _ := journalStacks(entries)
+	if len(undo_stack) == 0 {
+		return JournalEntry{}, ErrNothingToUndo
+	}
+
+	entry := undo_stack[len(undo_stack)-1]
+	if movesArchive(entry) {
+		return entry, fmt.Errorf("undo %s: %w", entry, ErrArchiveNotUndoable)
+	}
+
+	switch {
+	case entry.Op == JOURNAL_REPLACE:
+		err = s.ProjectStore.Save(entry.AllBefore)
+	case entry.Before == nil:
+		err = s.ProjectStore.Delete(entry.After.ID)
+	default:
+		err = s.ProjectStore.Put(*entry.Before)
+		if err == nil && entry.After == nil {
+			err = addToGroups(entry.Before.ID, entry.Groups)
+		}
+	}
+
+	if err != nil && !errors.Is(err, ErrProjectNotFound) {
+		return entry, fmt.Errorf("undo %s: %w", entry, err)
+	}
+
+	return entry, s.append(JournalEntry{Op: JOURNAL_UNDO, Target: entry.Seq})
+}
+
+// Redo reapplies the most recently undone mutation.
+func (s *JournaledStore) Redo() (JournalEntry, error) {
+	entries, err := s.read()
+	if err != nil {
+		return JournalEntry{}, err
+	}
+
+	_, redo_stack := journalStacks(entries)
+	if len(redo_stack) == 0 {
+		return JournalEntry{}, ErrNothingToRedo
+	}
+
+	entry := redo_stack[len(redo_stack)-1]
+	if movesArchive(entry) {
+		return entry, fmt.Errorf("redo %s: %w", entry, ErrArchiveNotUndoable)
+	}
+
+	switch {
+	case entry.Op == JOURNAL_REPLACE:
+		err = s.ProjectStore.Save(entry.AllAfter)
+	case entry.After == nil:
+		err = s.ProjectStore.Delete(entry.Before.ID)
+		if err == nil {
+			err = RemoveFromGroups(entry.Before.ID)
+		}
+	default:
+		err = s.ProjectStore.Put(*entry.After)
+	}
+
+	if err != nil && !errors.Is(err, ErrProjectNotFound) {
+		return entry, fmt.Errorf("redo %s: %w", entry, err)
+	}
+
+	return entry, s.append(JournalEntry{Op: JOURNAL_REDO, Target: entry.Seq})
+}
+
+// movesArchive reports whether entry changed the archive file of a project,
+// which ArchiveProject and RestoreProject only do along with the directory.
+func movesArchive(entry JournalEntry) bool {
+	if entry.Before == nil || entry.After == nil {
+		return false
+	}
+
+	return entry.Before.ArchiveFile != entry.After.ArchiveFile
+}
+
+// journalStacks replays the journal into the mutations that can be undone
+// and the ones that can be redone, most recent last.
+func journalStacks(entries []JournalEntry) ([]JournalEntry, []JournalEntry) {
+	var undo_stack, redo_stack []JournalEntry
+
+	for _, entry := range entries {
+		switch entry.Op {
+		case JOURNAL_UNDO:
+			if len(undo_stack) > 0 {
+				redo_stack = append(redo_stack, undo_stack[len(undo_stack)-1])
+				undo_stack = undo_stack[:len(undo_stack)-1]
+			}
+		case JOURNAL_REDO:
+			if len(redo_stack) > 0 {
+				undo_stack = append(undo_stack, redo_stack[len(redo_stack)-1])
+				redo_stack = redo_stack[:len(redo_stack)-1]
+			}
+		default:
+			undo_stack = append(undo_stack, entry)
+			redo_stack = nil
+		}
+	}
+
+	return undo_stack, redo_stack
+}
+
+func (s *JournaledStore) getIfExists(id string) (*Project, error) {
+	project, err := s.ProjectStore.Get(id)
+	if errors.Is(err, ErrProjectNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+// read returns every entry of the journal. A missing journal is empty and
+// lines that don't parse, like one cut off by a crash, are skipped.
+func (s *JournaledStore) read() ([]JournalEntry, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Println("Skipping unreadable journal line: ", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (s *JournaledStore) append(entry JournalEntry) error {
+	if s.next_seq == 0 {
+		entries, err := s.read()
+		if err != nil {
+			return err
+		}
+
+		s.next_seq = 1
+		if len(entries) > 0 {
+			s.next_seq = entries[len(entries)-1].Seq + 1
+		}
+	}
+
+	entry.Seq = s.next_seq
+	s.next_seq++
+	entry.Time = time.Now().Format(time.RFC3339)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode journal entry: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open journal: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write journal: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync journal: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package project_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// newJournaledProject sets up a journaled store holding one project whose
+// directory exists, with archives going to a temp directory.
+func newJournaledProject(t *testing.T) (*project.JournaledStore, project.Project) {
+	t.Helper()
+
+	dir := t.TempDir()
+	project.SetArchiveDir(filepath.Join(dir, "archives"))
+
+	p := project.Project{ID: "01HZAAAAAAAAAAAAAAAAAAAAAA", Name: "alpha", Path: filepath.Join(dir, "alpha")}
+	if err := os.MkdirAll(p.Path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(p.Path, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := project.NewJournaledStore(project.NewMemoryStore(), filepath.Join(dir, "journal.jsonl"))
+	if err := store.Put(p); err != nil {
+		t.Fatal(err)
+	}
+
+	return store, p
+}
+
+func TestUndoArchive(t *testing.T) {
+	store, p := newJournaledProject(t)
+
+	if _, err := project.ArchiveProject(store, p.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Undo(); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+
+	got, err := store.Get(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Archived != "" {
+		t.Fatalf("project is still archived after undo: %+v", got)
+	}
+}
+
+func TestUndoCompressedArchive(t *testing.T) {
+	store, p := newJournaledProject(t)
+
+	archived, err := project.ArchiveProject(store, p.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Undo(); !errors.Is(err, project.ErrArchiveNotUndoable) {
+		t.Fatalf("Undo() error = %v, want ErrArchiveNotUndoable", err)
+	}
+
+	got, err := store.Get(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ArchiveFile != archived.ArchiveFile {
+		t.Fatalf("undo changed the record to %+v", got)
+	}
+
+	// Restoring unpacks the directory, undoing that is refused the same way
+	if _, err := project.RestoreProject(store, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(p.Path, "main.go")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Undo(); !errors.Is(err, project.ErrArchiveNotUndoable) {
+		t.Fatalf("Undo() after restore error = %v, want ErrArchiveNotUndoable", err)
+	}
+}
+
+func TestUndoRemoveRestoresGroups(t *testing.T) {
+	store, p := newJournaledProject(t)
+	project.SetGroupsFile(filepath.Join(t.TempDir(), "groups.json"))
+	defer project.SetGroupsFile(".groups.json")
+
+	other := "01HZBBBBBBBBBBBBBBBBBBBBBB"
+	for _, name := range []string{"work", "go"} {
+		if _, err := project.CreateGroup(name, []string{p.ID, other}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := project.CreateGroup("misc", []string{other}); err != nil {
+		t.Fatal(err)
+	}
+
+	memberOf := func() []string {
+		t.Helper()
+
+		groups, err := project.LoadGroups()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, g := range groups {
+			if g.HasMember(p.ID) {
+				names = append(names, g.Name)
+			}
+		}
+		return names
+	}
+
+	if err := project.RemoveProject(store, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if names := memberOf(); len(names) != 0 {
+		t.Fatalf("removed project is still in %v", names)
+	}
+
+	if _, err := store.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if names := memberOf(); !reflect.DeepEqual(names, []string{"go", "work"}) {
+		t.Fatalf("after undo the project is in %v, want [go work]", names)
+	}
+
+	if _, err := store.Redo(); err != nil {
+		t.Fatal(err)
+	}
+	if names := memberOf(); len(names) != 0 {
+		t.Fatalf("after redo the project is still in %v", names)
+	}
+}
@@ -1,11 +1,12 @@
 package project
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
@@ -14,11 +15,25 @@ import (
 )
 
 type Project struct {
-	ID          int    `json:"ID"`
-	Name        string `json:"Name"`
-	Description string `json:"Description"`
-	Path        string `json:"Path"`
-	TimeStamp   string `json:"TimeStamp"`
+	ID          string   `json:"ID"`
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	Path        string   `json:"Path"`
+	TimeStamp   string   `json:"TimeStamp"`
+	Tags        []string `json:"Tags,omitempty"`
+	// Pinned is the place of the project among the pinned ones, from 1.
+	// 0 means it isn't pinned.
+	Pinned int `json:"Pinned,omitempty"`
+	// Archived is when the project was archived, empty if it wasn't.
+	Archived string `json:"Archived,omitempty"`
+	// ArchiveFile is the tar.gz file the directory of an archived project
+	// was packed into, empty if it was left in place.
+	ArchiveFile string `json:"ArchiveFile,omitempty"`
+	// Notes are free-form Markdown.
+	Notes     string          `json:"Notes,omitempty"`
+	Checklist []ChecklistItem `json:"Checklist,omitempty"`
+	// Fields are the custom fields of the project by name, see FieldDef.
+	Fields map[string]string `json:"Fields,omitempty"`
 }
 
 func CheckDuplicateNames(projects *[]Project, name string) bool {
@@ -34,45 +49,7 @@ func CheckDuplicateNames(projects *[]Project, name string) bool {
 	return true
 }
 
-func ReadProjectsFromFile() []Project {
-	log.Println("Read Projects From File")
-
-	file, err := os.ReadFile(".projects.json")
-	if err != nil {
-		log.Println(err)
-		return nil
-	}
-
-	var projects []Project
-	err = json.Unmarshal(file, &projects)
-	if err != nil {
-		log.Println(err)
-		return nil
-	}
-
-	return projects
-}
-
-func SaveProjects(projects *[]Project) {
-	log.Println("Save Projects\n+-------------------+")
-
-	for i := range *projects {
-		(*projects)[i].ID = i
-	}
-
-	projectsJSON, err := json.MarshalIndent(projects, "", "  ")
-	if err != nil {
-		log.Println(err)
-		return
-	}
-
-	err = os.WriteFile(".projects.json", projectsJSON, 0644)
-	if err != nil {
-		log.Println(err)
-	}
-}
-
-func AddProject(projects *[]Project, name, description, path string) Project {
+func AddProject(store ProjectStore, name, description, path string, tags []string, fields map[string]string) (Project, error) {
 	log.Println("Add Project")
 
 	if path[len(path)-1] != '/' {
@@ -86,23 +63,23 @@ func AddProject(projects *[]Project, name, description, path string) Project {
 		Description: description,
 		Path:        path,
 		TimeStamp:   time.Now().Format(time.RFC3339),
-		ID:          len(*projects),
+		ID:          NewProjectID(),
+		Tags:        NormalizeTags(tags),
 	}
 
-	*projects = append(*projects, new_project)
+	if err := ApplyFields(&new_project, fields); err != nil {
+		return Project{}, err
+	}
 
 	if info, err := os.Stat(path); os.IsNotExist(err) {
 		err = os.Mkdir(path, 0755)
 		if err != nil {
-			log.Println("Error while creating project directory: ", err)
-			return Project{}
+			return Project{}, fmt.Errorf("create project directory: %w", err)
 		}
 	} else if err != nil {
-		log.Println("Error while checking if project directory exists: ", err)
-		return Project{}
+		return Project{}, fmt.Errorf("check project directory: %w", err)
 	} else if !info.IsDir() {
-		log.Println("Error: selected path exists but is not a directory")
-		return Project{}
+		return Project{}, fmt.Errorf("selected path exists but is not a directory: %s", path)
 	}
 
 	if info, err := os.Stat(path + "/.git"); os.IsNotExist(err) {
@@ -111,22 +88,63 @@ func AddProject(projects *[]Project, name, description, path string) Project {
 		err = cmd.Run()
 
 		if err != nil {
-			log.Println("Error while initializing git repository")
-			return Project{}
+			return Project{}, fmt.Errorf("initialize git repository: %w", err)
 		}
 	} else if err != nil {
-		log.Println("Error while checking if .git directory exists: ", err)
-		return Project{}
+		return Project{}, fmt.Errorf("check .git directory: %w", err)
 	} else if !info.IsDir() {
-		log.Println("WTF?!!!??! WHY IS .git NOT A DIRECTORY???!?!?!?")
-		return Project{}
+		return Project{}, fmt.Errorf("%s/.git exists but is not a directory", path)
 	}
 
 	path_manager.AddRecentPath(path)
 
-	SaveProjects(projects)
+	if err := store.Put(new_project); err != nil {
+		return Project{}, err
+	}
 
-	return new_project
+	return new_project, nil
+}
+
+// LinkProject registers a directory that already exists as a project,
+// without creating anything in it. An empty name means the directory name.
+func LinkProject(store ProjectStore, name, description, path string, tags []string, fields map[string]string) (Project, error) {
+	log.Println("Link Project")
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Project{}, fmt.Errorf("resolve project directory: %w", err)
+	}
+
+	if info, err := os.Stat(path); err != nil {
+		return Project{}, fmt.Errorf("check project directory: %w", err)
+	} else if !info.IsDir() {
+		return Project{}, fmt.Errorf("selected path exists but is not a directory: %s", path)
+	}
+
+	if name == "" {
+		name = filepath.Base(path)
+	}
+
+	new_project := Project{
+		Name:        name,
+		Description: description,
+		Path:        filepath.ToSlash(path),
+		TimeStamp:   time.Now().Format(time.RFC3339),
+		ID:          NewProjectID(),
+		Tags:        NormalizeTags(tags),
+	}
+
+	if err := ApplyFields(&new_project, fields); err != nil {
+		return Project{}, err
+	}
+
+	path_manager.AddRecentPath(new_project.Path)
+
+	if err := store.Put(new_project); err != nil {
+		return Project{}, err
+	}
+
+	return new_project, nil
 }
 
 func PrintProjectsSlice(projects []Project) string {
@@ -147,93 +165,131 @@ func PrintCompressedProjectsSlice(projects []Project) string {
 	var display_string string
 
 	for _, project := range projects {
-		display_string += fmt.Sprintf("ID: %d, Name: %s, Path: %s\n", project.ID, project.Name, project.Path)
+		display_string += fmt.Sprintf("ID: %s, Name: %s, Path: %s", project.ID, project.Name, project.Path)
+		if len(project.Tags) > 0 {
+			display_string += fmt.Sprintf(", Tags: %s", strings.Join(project.Tags, ", "))
+		}
+		for _, def := range field_schema {
+			if value, ok := project.Fields[def.Name]; ok {
+				display_string += fmt.Sprintf(", %s: %s", def.Name, value)
+			}
+		}
+		if open := OpenChecklistItems(project); open > 0 {
+			display_string += fmt.Sprintf(", Open Items: %d", open)
+		}
+		display_string += "\n"
 	}
 
 	return display_string
 }
 
 func PrintProjectInfo(project Project) string {
-	var project_info = fmt.Sprintf("Project Info:\nID: %d\nName: %s\nDescription: %s\nPath: %s\nCreate Timestamp: %s\n",
-		project.ID, project.Name, project.Description, project.Path, project.TimeStamp)
+	var project_info = fmt.Sprintf("Project Info:\nID: %s\nName: %s\nDescription: %s\nPath: %s\nTags: %s\nCreate Timestamp: %s\n",
+		project.ID, project.Name, project.Description, project.Path, strings.Join(project.Tags, ", "), project.TimeStamp)
+
+	project_info += PrintFields(project)
+
+	if project.Archived != "" {
+		project_info += fmt.Sprintf("Archived: %s\n", project.Archived)
+	}
+	if project.ArchiveFile != "" {
+		project_info += fmt.Sprintf("Archive File: %s\n", project.ArchiveFile)
+	}
 
 	return project_info
 }
 
-func RemoveProject(projects []Project, id int) []Project {
-	log.Println("Remove Project From Slice By ID")
-
-	err := os.RemoveAll(projects[id].Path)
+// RemoveProject removes the project from the store and the path history.
+// Its directory, or archive file, is left where it is.
+func RemoveProject(store ProjectStore, id string) error {
+	log.Println("Remove Project By ID")
 
+	removed, err := store.Get(id)
 	if err != nil {
-		log.Println("Failed to remove project folder", err)
+		return err
 	}
 
-	for i := range projects {
-		if projects[i].ID == id {
-			projects = append(projects[:i], projects[i+1:]...)
-			break
-		}
+	if err := store.Delete(id); err != nil {
+		return err
 	}
 
-	for i := range projects {
-		projects[i].ID = i
-	}
+	path_manager.RemovePath(removed.Path)
 
-	path_manager.RemovePath(projects[id].Path)
+	if err := RemoveFromGroups(id); err != nil {
+		log.Println("Error while removing project from groups: ", err)
+	}
 
-	return projects
+	return nil
 }
 
-func UpdateProject(projects []Project, id int, name, description, path string) []Project {
+// UpdateProject changes the fields of the project that are given. Empty
+// strings and nil tags keep the current value, empty tags remove them all.
+// Custom fields not in fields are kept, empty values remove them.
+func UpdateProject(store ProjectStore, id string, name, description, path string, tags []string, fields map[string]string) error {
 	log.Println("Update Project By ID")
 
-	for i, project := range projects {
-		if project.ID == id {
-			if name != "" {
-				projects[i].Name = name
-			}
-			if description != "" {
-				projects[i].Description = description
-			}
-			if path != "" {
-				projects[i].Path = path
-			}
-			projects[i].TimeStamp = time.Now().Format(time.RFC3339)
-			break
-		}
+	project, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if name != "" {
+		project.Name = name
+	}
+	if description != "" {
+		project.Description = description
+	}
+	if path != "" {
+		project.Path = path
 	}
+	if tags != nil {
+		project.Tags = NormalizeTags(tags)
+	}
+	if err := ApplyFields(&project, fields); err != nil {
+		return err
+	}
+	project.TimeStamp = time.Now().Format(time.RFC3339)
 
-	return projects
+	return store.Put(project)
 }
 
-func OpenProjectInExplorer(path string) {
+func OpenProjectInExplorer(path string) error {
 	log.Println("Open Project In Explorer")
 
-	cmd := exec.Command("start", path)
-	err := cmd.Run()
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("explorer", filepath.FromSlash(path))
+	case "darwin":
+		cmd = exec.Command("open", path)
+	default:
+		cmd = exec.Command("xdg-open", path)
+	}
 
-	if err != nil {
-		log.Fatal("Error while opening project in file explorer: ", err)
+	// explorer exits with 1 even when it opened the folder
+	if err := cmd.Run(); err != nil && runtime.GOOS != "windows" {
+		log.Println("Error while opening project in file explorer: ", err)
+		return fmt.Errorf("open file explorer: %w", err)
 	}
+
+	return nil
 }
 
-func OpenProjectInVSCode(path string) {
+// OpenProjectInVSCode opens the project in the configured editor, VS Code
+// unless SetEditor was called.
+func OpenProjectInVSCode(path string) error {
 	log.Println("Open Project In VSCode")
 
-	cmd := exec.Command("code", path)
-	err := cmd.Run()
-
-	if err != nil {
-		log.Fatal("Error while opening project in vs code: ", err)
-	}
+	return openInEditor(path)
 }
 
-func CopyProjectPath(path string) {
+func CopyProjectPath(path string) error {
 	err := clipboard.WriteAll(path)
 	if err != nil {
 		log.Printf("Failed to write to clipboard: %v\n", err)
-		panic(err)
+		return fmt.Errorf("copy path: %w", err)
 	}
 	log.Println("Successfully copied to clipboard!")
+
+	return nil
 }
@@ -0,0 +1,106 @@
+package project
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MergeConflict is a project that was changed both here and by another
+// instance since the registry was last read.
+type MergeConflict struct {
+	ID     string
+	Name   string
+	Reason string
+}
+
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("%s (ID: %s): %s", c.Name, c.ID, c.Reason)
+}
+
+// ConflictReporter is implemented by stores that merge edits made by other
+// instances and can report the projects that conflicted.
+type ConflictReporter interface {
+	TakeConflicts() []MergeConflict
+}
+
+/*
+MergeProjects does a three-way merge of two edited copies of the registry,
+keyed by project ID.
+
+Parameters:
+- base: The registry both sides started from.
+- ours: The registry as edited by this instance.
+- theirs: The registry as it is on disk now.
+
+Returns:
+- []Project: The merged registry, in the order of ours followed by projects only theirs has.
+- []MergeConflict: Projects both sides changed differently.
+
+Edits win over removals, and when both sides edited a project our version wins.
+*/
+func MergeProjects(base, ours, theirs []Project) ([]Project, []MergeConflict) {
+	base_by_id := indexProjects(base)
+	ours_by_id := indexProjects(ours)
+	theirs_by_id := indexProjects(theirs)
+
+	var merged []Project
+	var conflicts []MergeConflict
+
+	for _, our := range ours {
+		original, in_base := base_by_id[our.ID]
+		their, in_theirs := theirs_by_id[our.ID]
+
+		switch {
+		case !in_base && !in_theirs:
+			merged = append(merged, our)
+		case !in_base:
+			merged = append(merged, our)
+			if !reflect.DeepEqual(our, their) {
+				conflicts = append(conflicts, MergeConflict{our.ID, our.Name, "added by both instances, kept this one"})
+			}
+		case !in_theirs:
+			if reflect.DeepEqual(our, original) {
+				continue
+			}
+			merged = append(merged, our)
+			conflicts = append(conflicts, MergeConflict{our.ID, our.Name, "removed by another instance but edited here, kept it"})
+		case reflect.DeepEqual(our, original):
+			merged = append(merged, their)
+		case reflect.DeepEqual(their, original), reflect.DeepEqual(our, their):
+			merged = append(merged, our)
+		default:
+			merged = append(merged, our)
+			conflicts = append(conflicts, MergeConflict{our.ID, our.Name, "edited by both instances, kept the edit made here"})
+		}
+	}
+
+	for _, their := range theirs {
+		if _, in_ours := ours_by_id[their.ID]; in_ours {
+			continue
+		}
+
+		original, in_base := base_by_id[their.ID]
+
+		switch {
+		case !in_base:
+			merged = append(merged, their)
+		case reflect.DeepEqual(their, original):
+			// removed here
+		default:
+			merged = append(merged, their)
+			conflicts = append(conflicts, MergeConflict{their.ID, their.Name, "removed here but edited by another instance, kept it"})
+		}
+	}
+
+	return merged, conflicts
+}
+
+func indexProjects(projects []Project) map[string]Project {
+	by_id := make(map[string]Project, len(projects))
+
+	for _, project := range projects {
+		by_id[project.ID] = project
+	}
+
+	return by_id
+}
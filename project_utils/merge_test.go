@@ -0,0 +1,150 @@
+package project_test
+
+import (
+	"reflect"
+	"testing"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+	"github.com/yur4uwe/cmd-project-manager/project_utils/storetest"
+)
+
+func TestMergeProjects(t *testing.T) {
+	sample := storetest.Sample()
+	alpha, beta, gamma := sample[0], sample[1], sample[2]
+
+	edited := func(p project.Project, description string) project.Project {
+		p.Description = description
+		return p
+	}
+	delta := project.Project{ID: "01HZDDDDDDDDDDDDDDDDDDDDDD", Name: "delta", Path: "/work/delta"}
+	epsilon := project.Project{ID: "01HZEEEEEEEEEEEEEEEEEEEEEE", Name: "epsilon", Path: "/work/epsilon"}
+
+	base := []project.Project{alpha, beta, gamma}
+
+	cases := []struct {
+		name      string
+		ours      []project.Project
+		theirs    []project.Project
+		want      []project.Project
+		conflicts []string
+	}{
+		{
+			name:   "nothing changed",
+			ours:   base,
+			theirs: base,
+			want:   base,
+		},
+		{
+			name:   "edited here",
+			ours:   []project.Project{edited(alpha, "ours"), beta, gamma},
+			theirs: base,
+			want:   []project.Project{edited(alpha, "ours"), beta, gamma},
+		},
+		{
+			name:   "edited there",
+			ours:   base,
+			theirs: []project.Project{alpha, edited(beta, "theirs"), gamma},
+			want:   []project.Project{alpha, edited(beta, "theirs"), gamma},
+		},
+		{
+			name:   "different projects edited on both sides",
+			ours:   []project.Project{edited(alpha, "ours"), beta, gamma},
+			theirs: []project.Project{alpha, beta, edited(gamma, "theirs")},
+			want:   []project.Project{edited(alpha, "ours"), beta, edited(gamma, "theirs")},
+		},
+		{
+			name:   "same edit on both sides",
+			ours:   []project.Project{edited(alpha, "same"), beta, gamma},
+			theirs: []project.Project{edited(alpha, "same"), beta, gamma},
+			want:   []project.Project{edited(alpha, "same"), beta, gamma},
+		},
+		{
+			name:      "concurrent edits",
+			ours:      []project.Project{edited(alpha, "ours"), beta, gamma},
+			theirs:    []project.Project{edited(alpha, "theirs"), beta, gamma},
+			want:      []project.Project{edited(alpha, "ours"), beta, gamma},
+			conflicts: []string{alpha.ID},
+		},
+		{
+			name:   "added here",
+			ours:   []project.Project{alpha, beta, gamma, delta},
+			theirs: base,
+			want:   []project.Project{alpha, beta, gamma, delta},
+		},
+		{
+			name:   "added on each side",
+			ours:   []project.Project{alpha, beta, gamma, delta},
+			theirs: []project.Project{alpha, beta, gamma, epsilon},
+			want:   []project.Project{alpha, beta, gamma, delta, epsilon},
+		},
+		{
+			name:   "same add on both sides",
+			ours:   []project.Project{alpha, beta, gamma, delta},
+			theirs: []project.Project{alpha, beta, gamma, delta},
+			want:   []project.Project{alpha, beta, gamma, delta},
+		},
+		{
+			name:      "different adds on both sides",
+			ours:      []project.Project{alpha, beta, gamma, edited(delta, "ours")},
+			theirs:    []project.Project{alpha, beta, gamma, edited(delta, "theirs")},
+			want:      []project.Project{alpha, beta, gamma, edited(delta, "ours")},
+			conflicts: []string{delta.ID},
+		},
+		{
+			name:   "removed here",
+			ours:   []project.Project{alpha, gamma},
+			theirs: base,
+			want:   []project.Project{alpha, gamma},
+		},
+		{
+			name:   "removed there",
+			ours:   base,
+			theirs: []project.Project{alpha, gamma},
+			want:   []project.Project{alpha, gamma},
+		},
+		{
+			name:   "removed on both sides",
+			ours:   []project.Project{alpha, gamma},
+			theirs: []project.Project{alpha, gamma},
+			want:   []project.Project{alpha, gamma},
+		},
+		{
+			name:      "removed there, edited here",
+			ours:      []project.Project{alpha, edited(beta, "ours"), gamma},
+			theirs:    []project.Project{alpha, gamma},
+			want:      []project.Project{alpha, edited(beta, "ours"), gamma},
+			conflicts: []string{beta.ID},
+		},
+		{
+			name:      "removed here, edited there",
+			ours:      []project.Project{alpha, gamma},
+			theirs:    []project.Project{alpha, edited(beta, "theirs"), gamma},
+			want:      []project.Project{alpha, gamma, edited(beta, "theirs")},
+			conflicts: []string{beta.ID},
+		},
+		{
+			name:   "order of ours first",
+			ours:   []project.Project{gamma, alpha, beta},
+			theirs: []project.Project{beta, epsilon, alpha, gamma},
+			want:   []project.Project{gamma, alpha, beta, epsilon},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			merged, conflicts := project.MergeProjects(base, c.ours, c.theirs)
+
+			if !reflect.DeepEqual(merged, c.want) {
+				t.Errorf("merged\n%+v\nwant\n%+v", merged, c.want)
+			}
+
+			var ids []string
+			for _, conflict := range conflicts {
+				ids = append(ids, conflict.ID)
+			}
+			if !reflect.DeepEqual(ids, c.conflicts) {
+				t.Errorf("conflicts %v, want %v", conflicts, c.conflicts)
+			}
+		})
+	}
+}
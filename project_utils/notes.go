@@ -0,0 +1,167 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ChecklistItem is one entry of the checklist of a project.
+type ChecklistItem struct {
+	Text string `json:"Text"`
+	Done bool   `json:"Done"`
+}
+
+func (item ChecklistItem) String() string {
+	if item.Done {
+		return "[x] " + item.Text
+	}
+
+	return "[ ] " + item.Text
+}
+
+// ParseChecklistItem reads an item written by String. Text without a box
+// is an open item.
+func ParseChecklistItem(line string) ChecklistItem {
+	line = strings.TrimSpace(line)
+
+	switch {
+	case strings.HasPrefix(line, "[x] "), strings.HasPrefix(line, "[X] "):
+		return ChecklistItem{Text: strings.TrimSpace(line[4:]), Done: true}
+	case strings.HasPrefix(line, "[ ] "):
+		return ChecklistItem{Text: strings.TrimSpace(line[4:])}
+	}
+
+	return ChecklistItem{Text: line}
+}
+
+// OpenChecklistItems counts the items of the checklist that aren't done.
+func OpenChecklistItems(p Project) int {
+	open := 0
+	for _, item := range p.Checklist {
+		if !item.Done {
+			open++
+		}
+	}
+
+	return open
+}
+
+// AddChecklistItem appends an open item to the checklist of p.
+func AddChecklistItem(p *Project, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("checklist item is empty")
+	}
+
+	p.Checklist = append(p.Checklist, ChecklistItem{Text: text})
+
+	return nil
+}
+
+// ToggleChecklistItem marks item number n, counted from 1, done or open again.
+func ToggleChecklistItem(p *Project, n int) error {
+	if n < 1 || n > len(p.Checklist) {
+		return checklistRangeError(*p, n)
+	}
+
+	p.Checklist[n-1].Done = !p.Checklist[n-1].Done
+
+	return nil
+}
+
+// RemoveChecklistItem removes item number n, counted from 1.
+func RemoveChecklistItem(p *Project, n int) error {
+	if n < 1 || n > len(p.Checklist) {
+		return checklistRangeError(*p, n)
+	}
+
+	p.Checklist = append(p.Checklist[:n-1:n-1], p.Checklist[n:]...)
+	if len(p.Checklist) == 0 {
+		p.Checklist = nil
+	}
+
+	return nil
+}
+
+func checklistRangeError(p Project, n int) error {
+	return fmt.Errorf("%s has no checklist item %d, it has %d", p.Name, n, len(p.Checklist))
+}
+
+// PrintNotes prints the notes of the project followed by its numbered
+// checklist.
+func PrintNotes(p Project) string {
+	var notes string
+
+	if strings.TrimSpace(p.Notes) == "" {
+		notes = "No notes.\n"
+	} else {
+		notes = strings.TrimRight(p.Notes, "\n") + "\n"
+	}
+
+	if len(p.Checklist) > 0 {
+		notes += fmt.Sprintf("\nChecklist (%d open):\n", OpenChecklistItems(p))
+		for i, item := range p.Checklist {
+			notes += fmt.Sprintf("%2d. %s\n", i+1, item)
+		}
+	}
+
+	return notes
+}
+
+/*
+EditNotes opens notes in the editor of the user, $VISUAL or $EDITOR, and
+falls back to notepad on Windows and vi elsewhere. The editor runs on the
+terminal of the process.
+
+Returns:
+- string: The notes as the editor saved them.
+- error: An error if the editor could not be run or exited with an error. The notes are unchanged then.
+*/
+func EditNotes(notes string) (string, error) {
+	file, err := os.CreateTemp("", "pm-notes-*.md")
+	if err != nil {
+		return notes, fmt.Errorf("create notes file: %w", err)
+	}
+	path := file.Name()
+	defer os.Remove(path)
+
+	_, err = file.WriteString(notes)
+	if close_err := file.Close(); err == nil {
+		err = close_err
+	}
+	if err != nil {
+		return notes, fmt.Errorf("write notes file: %w", err)
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// Editors are often set with arguments, like "code --wait"
+	args := append(strings.Fields(editor), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return notes, fmt.Errorf("run editor %s: %w", editor, err)
+	}
+
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return notes, fmt.Errorf("read notes file: %w", err)
+	}
+
+	return string(edited), nil
+}
@@ -0,0 +1,97 @@
+package project
+
+import (
+	"log"
+	"sort"
+)
+
+// PinnedFirst returns the indexes of projects with the pinned projects
+// first, in pin order, followed by the others in their order.
+func PinnedFirst(projects []Project) []int {
+	order := make([]int, len(projects))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := projects[order[i]].Pinned, projects[order[j]].Pinned
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+
+	return order
+}
+
+// SortPinned returns a copy of projects with the pinned projects first.
+func SortPinned(projects []Project) []Project {
+	sorted := make([]Project, 0, len(projects))
+	for _, i := range PinnedFirst(projects) {
+		sorted = append(sorted, projects[i])
+	}
+
+	return sorted
+}
+
+// PinnedProjects returns only the pinned projects, in pin order.
+func PinnedProjects(projects []Project) []Project {
+	var pinned []Project
+	for _, p := range SortPinned(projects) {
+		if p.Pinned == 0 {
+			break
+		}
+		pinned = append(pinned, p)
+	}
+
+	return pinned
+}
+
+// PinProject pins the project after the ones pinned already. Pinning a
+// pinned project keeps its place.
+func PinProject(store ProjectStore, id string) error {
+	log.Println("Pin Project By ID")
+
+	projects, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	last := 0
+	for _, p := range projects {
+		if p.Pinned > last {
+			last = p.Pinned
+		}
+	}
+
+	pinned, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if pinned.Pinned != 0 {
+		return nil
+	}
+
+	pinned.Pinned = last + 1
+
+	return store.Put(pinned)
+}
+
+// UnpinProject unpins the project. The others keep their order.
+func UnpinProject(store ProjectStore, id string) error {
+	log.Println("Unpin Project By ID")
+
+	unpinned, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if unpinned.Pinned == 0 {
+		return nil
+	}
+
+	unpinned.Pinned = 0
+
+	return store.Put(unpinned)
+}
@@ -0,0 +1,63 @@
+package project
+
+import (
+	"sort"
+	"time"
+
+	path_manager "github.com/yur4uwe/cmd-project-manager/manage_paths"
+)
+
+// ProjectQuery narrows down and orders a listing of projects.
+type ProjectQuery struct {
+	// Tags keeps the projects carrying every one of them.
+	Tags []string
+	// Since keeps the projects created or updated at or after it, unless zero.
+	Since time.Time
+	// ByOpened orders the projects by how often their directory was opened,
+	// most first. Otherwise they are in registry order.
+	ByOpened bool
+}
+
+// ProjectQuerier is a store that answers a ProjectQuery itself, from its
+// indexes, instead of going through every project.
+type ProjectQuerier interface {
+	QueryProjects(query ProjectQuery) ([]Project, error)
+}
+
+// QueryProjects answers query on projects in memory, for stores that aren't
+// a ProjectQuerier. Projects with a timestamp that can't be parsed are kept
+// out of Since queries.
+func QueryProjects(projects []Project, query ProjectQuery) []Project {
+	projects = FilterByTags(projects, query.Tags)
+
+	if !query.Since.IsZero() {
+		var recent []Project
+		for _, p := range projects {
+			stamp, err := time.Parse(time.RFC3339, p.TimeStamp)
+			if err == nil && !stamp.Before(query.Since) {
+				recent = append(recent, p)
+			}
+		}
+		projects = recent
+	}
+
+	if query.ByOpened {
+		recent_paths, _ := path_manager.ReadRecentPathsFromFile()
+
+		opened := make(map[string]path_manager.RecentPath, len(recent_paths))
+		for _, recent_path := range recent_paths {
+			opened[recent_path.Path] = recent_path
+		}
+
+		projects = append([]Project(nil), projects...)
+		sort.SliceStable(projects, func(i, j int) bool {
+			a, b := opened[projects[i].Path], opened[projects[j].Path]
+			if a.TimesOpened != b.TimesOpened {
+				return a.TimesOpened > b.TimesOpened
+			}
+			return a.LastAccess > b.LastAccess
+		})
+	}
+
+	return projects
+}
@@ -0,0 +1,100 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// CorruptRegistryError is returned by Load when the registry file exists
+// but could not be parsed.
+type CorruptRegistryError struct {
+	Path           string
+	QuarantinePath string
+	Recovered      []Project
+	Err            error
+}
+
+func (e *CorruptRegistryError) Error() string {
+	return fmt.Sprintf("projects file %s is corrupt: %v", e.Path, e.Err)
+}
+
+func (e *CorruptRegistryError) Unwrap() error {
+	return e.Err
+}
+
+// quarantineFile writes a timestamped copy of a broken file next to it
+// and returns the path of the copy.
+func quarantineFile(path string, data []byte) (string, error) {
+	quarantine_path := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+
+	err := os.WriteFile(quarantine_path, data, 0644)
+	if err != nil {
+		return "", fmt.Errorf("quarantine %s: %w", path, err)
+	}
+
+	return quarantine_path, nil
+}
+
+// recoverProjects salvages every project object of a broken projects file
+// that still parses. Projects are the objects directly inside the legacy
+// bare array, or inside the projects array of the versioned envelope.
+// Objects nested in a project, like its custom fields, are never taken for
+// one. Truncated or mangled entries are skipped.
+func recoverProjects(data []byte) []Project {
+	var recovered []Project
+
+	// The brackets and braces enclosing the current position, and where
+	// each open object started
+	var open []byte
+	var starts []int
+
+	in_string := false
+	escaped := false
+
+	for i, char := range data {
+		if in_string {
+			if escaped {
+				escaped = false
+			} else if char == '\\' {
+				escaped = true
+			} else if char == '"' {
+				in_string = false
+			}
+			continue
+		}
+
+		switch char {
+		case '"':
+			in_string = true
+		case '[':
+			open = append(open, '[')
+		case ']':
+			if len(open) > 0 && open[len(open)-1] == '[' {
+				open = open[:len(open)-1]
+			}
+		case '{':
+			open = append(open, '{')
+			starts = append(starts, i)
+		case '}':
+			if len(open) == 0 || open[len(open)-1] != '{' {
+				continue
+			}
+			open = open[:len(open)-1]
+			start := starts[len(starts)-1]
+			starts = starts[:len(starts)-1]
+
+			if enclosing := string(open); enclosing != "[" && enclosing != "{[" {
+				continue
+			}
+
+			var project Project
+			if err := json.Unmarshal(data[start:i+1], &project); err == nil && project.Name != "" {
+				recovered = append(recovered, project)
+			}
+		}
+	}
+
+	return recovered
+}
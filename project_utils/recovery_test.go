@@ -0,0 +1,53 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecoverProjects(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "legacy array",
+			data: `[{"Name": "alpha", "Path": "/work/alpha"}, {"Name": "beta", "Pa`,
+			want: []string{"alpha"},
+		},
+		{
+			name: "envelope",
+			data: `{"version": 2, "projects": [{"Name": "alpha", "Path": "/work/alpha"}, {"Name": "beta", "Path": "/work/beta"}, {"Na`,
+			want: []string{"alpha", "beta"},
+		},
+		{
+			name: "nested objects in legacy array",
+			data: `[{"Name": "alpha", "Fields": {"Name": "not a project"}, "Checklist": [{"Text": "x", "Name": "item"}]}, {"Name": "beta", "Fields": {"Name": "cut`,
+			want: []string{"alpha"},
+		},
+		{
+			name: "nested objects in envelope",
+			data: `{"version": 2, "projects": [{"Name": "alpha", "Fields": {"Name": "not a project"}, "Checklist": [{"Text": "x", "Name": "item"}]}, {"Name": "beta", "Checklist": [{"Name": "cut`,
+			want: []string{"alpha"},
+		},
+		{
+			name: "braces in strings",
+			data: `[{"Name": "a{b", "Notes": "}]{\"Name\": \"fake\"}"}, {`,
+			want: []string{"a{b"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var names []string
+			for _, p := range recoverProjects([]byte(c.data)) {
+				names = append(names, p.Name)
+			}
+
+			if !reflect.DeepEqual(names, c.want) {
+				t.Fatalf("recovered %v, want %v", names, c.want)
+			}
+		})
+	}
+}
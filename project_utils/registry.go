@@ -0,0 +1,148 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentRegistryVersion is the format version written by JSONFileStore.Save.
+//
+// Version history:
+//   - 0: bare JSON array of projects with slice index IDs
+//   - 1: {"version": 1, "projects": [...]} envelope with permanent IDs
+const CurrentRegistryVersion = 1
+
+// registryFile is the on-disk layout of the projects file.
+type registryFile struct {
+	Version  int       `json:"version"`
+	Projects []Project `json:"projects"`
+}
+
+// MigrationReport describes what loading a projects file changed, or would
+// change, to bring it up to CurrentRegistryVersion.
+type MigrationReport struct {
+	FromVersion int
+	ToVersion   int
+	Changes     []string
+}
+
+func (r MigrationReport) NeedsMigration() bool {
+	return r.FromVersion != r.ToVersion || len(r.Changes) > 0
+}
+
+func (r MigrationReport) String() string {
+	if !r.NeedsMigration() {
+		return fmt.Sprintf("Projects file is up to date (version %d).\n", r.ToVersion)
+	}
+
+	report := fmt.Sprintf("Projects file version %d -> %d:\n", r.FromVersion, r.ToVersion)
+	for _, change := range r.Changes {
+		report += "  - " + change + "\n"
+	}
+
+	return report
+}
+
+// A registryMigration upgrades the raw envelope from version From to From+1
+// and returns a line for every change it made.
+type registryMigration struct {
+	From  int
+	Apply func(raw map[string]json.RawMessage) ([]string, error)
+}
+
+// registryMigrations must stay ordered by From with no gaps.
+var registryMigrations = []registryMigration{
+	{From: 0, Apply: migrateBareArray},
+}
+
+// DecodeRegistry parses a projects file of any known version and upgrades
+// it in memory to CurrentRegistryVersion.
+func DecodeRegistry(data []byte) ([]Project, MigrationReport, error) {
+	var report MigrationReport
+	var raw map[string]json.RawMessage
+
+	trimmed := bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(trimmed, []byte("[")) || bytes.Equal(trimmed, []byte("null")) {
+		raw = map[string]json.RawMessage{"projects": trimmed}
+	} else {
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return nil, report, err
+		}
+		if err := json.Unmarshal(raw["version"], &report.FromVersion); err != nil {
+			return nil, report, fmt.Errorf("read registry version: %w", err)
+		}
+	}
+
+	version := report.FromVersion
+	if version > CurrentRegistryVersion {
+		return nil, report, fmt.Errorf("projects file version %d is newer than supported version %d", version, CurrentRegistryVersion)
+	}
+
+	for _, migration := range registryMigrations {
+		if migration.From != version {
+			continue
+		}
+
+		changes, err := migration.Apply(raw)
+		if err != nil {
+			return nil, report, fmt.Errorf("migrate projects file from version %d: %w", version, err)
+		}
+
+		report.Changes = append(report.Changes, changes...)
+		version++
+	}
+
+	report.ToVersion = version
+
+	var projects []Project
+	if projects_json, ok := raw["projects"]; ok {
+		if err := json.Unmarshal(projects_json, &projects); err != nil {
+			return nil, report, err
+		}
+	}
+
+	return projects, report, nil
+}
+
+// EncodeRegistry returns projects in the current projects file format.
+func EncodeRegistry(projects []Project) ([]byte, error) {
+	if projects == nil {
+		projects = []Project{}
+	}
+
+	return json.MarshalIndent(registryFile{
+		Version:  CurrentRegistryVersion,
+		Projects: projects,
+	}, "", "  ")
+}
+
+// migrateBareArray wraps the legacy bare array in the versioned envelope
+// and replaces slice index IDs with permanent ones.
+func migrateBareArray(raw map[string]json.RawMessage) ([]string, error) {
+	changes := []string{"wrap the bare project list in a versioned envelope"}
+
+	var projects []Project
+	if err := json.Unmarshal(raw["projects"], &projects); err != nil {
+		return nil, err
+	}
+
+	for i := range projects {
+		if projects[i].ID != "" {
+			continue
+		}
+		projects[i].ID = legacyProjectID(projects[i], i)
+		changes = append(changes, fmt.Sprintf("project %q: assign permanent ID %s", projects[i].Name, projects[i].ID))
+	}
+
+	projects_json, err := json.Marshal(projects)
+	if err != nil {
+		return nil, err
+	}
+
+	raw["projects"] = projects_json
+	raw["version"] = json.RawMessage("1")
+
+	return changes, nil
+}
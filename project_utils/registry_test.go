@@ -0,0 +1,64 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// A registry from before versions and stable IDs, as old snapshots keep it
+const legacyRegistry = `[
+	{"ID": 0, "Name": "alpha", "Description": "", "Path": "/work/alpha", "TimeStamp": "2023-05-06T07:08:09Z"},
+	{"ID": 1, "Name": "beta", "Description": "", "Path": "/work/beta", "TimeStamp": "not a time"},
+	{"ID": 2, "Name": "beta", "Description": "", "Path": "/work/beta", "TimeStamp": "not a time"}
+]`
+
+func TestDecodeLegacyRegistryIsStable(t *testing.T) {
+	first, report, err := DecodeRegistry([]byte(legacyRegistry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.NeedsMigration() {
+		t.Fatal("legacy registry doesn't need migration")
+	}
+
+	second, _, err := DecodeRegistry([]byte(legacyRegistry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("decodes disagree:\n%+v\n%+v", first, second)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range first {
+		if len(p.ID) != 26 || seen[p.ID] {
+			t.Fatalf("ID %q is malformed or taken twice", p.ID)
+		}
+		seen[p.ID] = true
+	}
+}
+
+func TestLegacySnapshotMatchesMigratedRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.json")
+	if err := os.WriteFile(path, []byte(legacyRegistry), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := NewJSONFileStore(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot taken before the migration holds the legacy bytes
+	snapshot, _, err := DecodeRegistry([]byte(legacyRegistry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := DiffProjects(snapshot, migrated); len(changes) != 0 {
+		t.Fatalf("snapshot and migrated registry differ: %+v", changes)
+	}
+}
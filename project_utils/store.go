@@ -0,0 +1,387 @@
+package project
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	file_utils "github.com/yur4uwe/cmd-project-manager/file_utils"
+)
+
+var ErrProjectNotFound = errors.New("project not found")
+
+// ErrRegistryCorrupt is returned by Save while the registry file on disk
+// could not be parsed and the recovered entries were not accepted yet.
+var ErrRegistryCorrupt = errors.New("projects file is corrupt, refusing to overwrite it")
+
+// ProjectStore is the storage backend for the project registry.
+// Load and Save work with the whole registry, Get, Put, Delete and List
+// work with single records on top of whatever Load returned.
+type ProjectStore interface {
+	Load() ([]Project, error)
+	Save(projects []Project) error
+	Get(id string) (Project, error)
+	Put(project Project) error
+	Delete(id string) error
+	List() ([]Project, error)
+}
+
+// RecoverableStore is a store that can salvage entries from a corrupt
+// registry, see CorruptRegistryError.
+type RecoverableStore interface {
+	ProjectStore
+	AcceptRecovered() error
+}
+
+// MemoryStore keeps the registry in memory only. Used by tests and as the
+// cache behind JSONFileStore.
+type MemoryStore struct {
+	projects []Project
+}
+
+func NewMemoryStore(projects ...Project) *MemoryStore {
+	store := &MemoryStore{}
+	store.Save(projects)
+	return store
+}
+
+func (s *MemoryStore) Load() ([]Project, error) {
+	return s.List()
+}
+
+func (s *MemoryStore) Save(projects []Project) error {
+	s.projects = make([]Project, len(projects))
+	copy(s.projects, projects)
+
+	AssignMissingIDs(s.projects)
+
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (Project, error) {
+	for _, project := range s.projects {
+		if project.ID == id {
+			return project, nil
+		}
+	}
+
+	return Project{}, fmt.Errorf("get project %q: %w", id, ErrProjectNotFound)
+}
+
+// Put replaces the project with the same ID or appends it as a new one.
+// A project without an ID gets a new one.
+func (s *MemoryStore) Put(project Project) error {
+	if project.ID == "" {
+		project.ID = NewProjectID()
+	}
+
+	for i := range s.projects {
+		if s.projects[i].ID == project.ID {
+			s.projects[i] = project
+			return nil
+		}
+	}
+
+	s.projects = append(s.projects, project)
+
+	return nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	for i := range s.projects {
+		if s.projects[i].ID == id {
+			s.projects = append(s.projects[:i], s.projects[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("delete project %q: %w", id, ErrProjectNotFound)
+}
+
+func (s *MemoryStore) List() ([]Project, error) {
+	projects := make([]Project, len(s.projects))
+	copy(projects, s.projects)
+
+	return projects, nil
+}
+
+// JSONFileStore keeps the registry in a JSON file. Every mutation is
+// written through to the file right away.
+//
+// Several instances may share the file. Writes happen under an advisory
+// lock, and if the file changed since this instance last read it the edits
+// are merged into it with MergeProjects instead of overwriting it.
+type JSONFileStore struct {
+	path      string
+	cache     MemoryStore
+	base      []Project
+	disk_hash [sha256.Size]byte
+	loaded    bool
+	corrupt   bool
+	conflicts []MergeConflict
+}
+
+func NewJSONFileStore(path string) *JSONFileStore {
+	return &JSONFileStore{path: path}
+}
+
+// readRegistryFile returns the contents of the projects file and their
+// hash. A missing file reads as empty.
+func (s *JSONFileStore) readRegistryFile() ([]byte, [sha256.Size]byte, error) {
+	file, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, sha256.Sum256(nil), nil
+	} else if err != nil {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("read projects file: %w", err)
+	}
+
+	return file, sha256.Sum256(file), nil
+}
+
+// Load reads the registry from disk. A missing file is an empty registry.
+func (s *JSONFileStore) Load() ([]Project, error) {
+	log.Println("Read Projects From File")
+
+	file, hash, err := s.readRegistryFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var projects []Project
+	var report MigrationReport
+
+	if file != nil {
+		projects, report, err = DecodeRegistry(file)
+		if err != nil {
+			return nil, s.handleCorruptFile(file, err)
+		}
+	}
+
+	assigned_ids := AssignMissingIDs(projects)
+
+	s.setSynced(projects, hash)
+	s.corrupt = false
+
+	if report.NeedsMigration() || assigned_ids {
+		log.Print("Migrating projects file\n", report)
+		if err := s.Save(projects); err != nil {
+			return nil, fmt.Errorf("save migrated projects file: %w", err)
+		}
+	}
+
+	return s.cache.List()
+}
+
+// setSynced records projects as the registry currently on disk.
+func (s *JSONFileStore) setSynced(projects []Project, hash [sha256.Size]byte) {
+	s.cache.Save(projects)
+	s.base, _ = s.cache.List()
+	s.disk_hash = hash
+	s.loaded = true
+}
+
+// Migrate upgrades the projects file on disk to CurrentRegistryVersion.
+// With dry_run set it only reports what would change.
+func (s *JSONFileStore) Migrate(dry_run bool) (MigrationReport, error) {
+	report := MigrationReport{FromVersion: CurrentRegistryVersion, ToVersion: CurrentRegistryVersion}
+
+	file, hash, err := s.readRegistryFile()
+	if err != nil || file == nil {
+		return report, err
+	}
+
+	projects, report, err := DecodeRegistry(file)
+	if err != nil {
+		return report, fmt.Errorf("parse projects file %s: %w", s.path, err)
+	}
+
+	if dry_run || !report.NeedsMigration() {
+		return report, nil
+	}
+
+	s.setSynced(projects, hash)
+	s.corrupt = false
+	if err := s.Save(projects); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// handleCorruptFile quarantines an unparsable registry file, salvages what
+// it can and locks the store against saving until AcceptRecovered is called.
+func (s *JSONFileStore) handleCorruptFile(file []byte, parse_err error) error {
+	log.Println("Projects file is corrupt: ", parse_err)
+
+	corrupt_err := &CorruptRegistryError{
+		Path: s.path,
+		Err:  parse_err,
+	}
+
+	quarantine_path, err := quarantineFile(s.path, file)
+	if err != nil {
+		log.Println("Failed to quarantine corrupt projects file: ", err)
+	}
+	corrupt_err.QuarantinePath = quarantine_path
+	corrupt_err.Recovered = recoverProjects(file)
+
+	s.setSynced(corrupt_err.Recovered, sha256.Sum256(file))
+	s.corrupt = true
+
+	return corrupt_err
+}
+
+// AcceptRecovered replaces the corrupt registry with the entries that were
+// salvaged from it. The original file stays in its quarantine copy.
+func (s *JSONFileStore) AcceptRecovered() error {
+	if !s.corrupt {
+		return nil
+	}
+
+	s.corrupt = false
+	projects, _ := s.cache.List()
+
+	return s.Save(projects)
+}
+
+// Save writes projects to disk. If another instance changed the file since
+// it was last read here, projects is merged into its version first and the
+// conflicts are kept for TakeConflicts.
+func (s *JSONFileStore) Save(projects []Project) error {
+	log.Println("Save Projects\n+-------------------+")
+
+	if s.corrupt {
+		return ErrRegistryCorrupt
+	}
+
+	lock, err := file_utils.LockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	file, hash, err := s.readRegistryFile()
+	if err != nil {
+		return err
+	}
+
+	if s.loaded && hash != s.disk_hash {
+		log.Println("Projects file changed on disk, merging")
+
+		theirs, _, err := DecodeRegistry(file)
+		if err != nil {
+			return fmt.Errorf("projects file was changed by another instance and can't be read: %w", err)
+		}
+
+		var conflicts []MergeConflict
+		projects, conflicts = MergeProjects(s.base, projects, theirs)
+		s.conflicts = append(s.conflicts, conflicts...)
+	}
+
+	s.cache.Save(projects)
+	saved, _ := s.cache.List()
+
+	projectsJSON, err := EncodeRegistry(saved)
+	if err != nil {
+		return fmt.Errorf("encode projects: %w", err)
+	}
+
+	err = file_utils.WriteFileAtomic(s.path, projectsJSON, 0644)
+	if err != nil {
+		return fmt.Errorf("write projects file: %w", err)
+	}
+
+	s.setSynced(saved, sha256.Sum256(projectsJSON))
+
+	return nil
+}
+
+// TakeConflicts returns the merge conflicts of the saves since the last
+// call and forgets them.
+func (s *JSONFileStore) TakeConflicts() []MergeConflict {
+	conflicts := s.conflicts
+	s.conflicts = nil
+
+	return conflicts
+}
+
+// ensureLoaded loads the registry on first use and afterwards picks up
+// changes other instances wrote to the file.
+func (s *JSONFileStore) ensureLoaded() error {
+	if !s.loaded {
+		_, err := s.Load()
+		return err
+	}
+
+	if s.corrupt {
+		return nil
+	}
+
+	file, hash, err := s.readRegistryFile()
+	if err != nil || hash == s.disk_hash {
+		return err
+	}
+
+	theirs, _, err := DecodeRegistry(file)
+	if err != nil {
+		log.Println("Projects file changed on disk and can't be read: ", err)
+		return nil
+	}
+
+	ours, _ := s.cache.List()
+	merged, conflicts := MergeProjects(s.base, ours, theirs)
+	s.conflicts = append(s.conflicts, conflicts...)
+
+	s.setSynced(theirs, hash)
+	s.cache.Save(merged)
+
+	return nil
+}
+
+func (s *JSONFileStore) Get(id string) (Project, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return Project{}, err
+	}
+
+	return s.cache.Get(id)
+}
+
+func (s *JSONFileStore) Put(project Project) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	if s.corrupt {
+		return ErrRegistryCorrupt
+	}
+
+	s.cache.Put(project)
+	projects, _ := s.cache.List()
+
+	return s.Save(projects)
+}
+
+func (s *JSONFileStore) Delete(id string) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	if s.corrupt {
+		return ErrRegistryCorrupt
+	}
+
+	if err := s.cache.Delete(id); err != nil {
+		return err
+	}
+	projects, _ := s.cache.List()
+
+	return s.Save(projects)
+}
+
+func (s *JSONFileStore) List() ([]Project, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	return s.cache.List()
+}
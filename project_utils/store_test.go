@@ -0,0 +1,69 @@
+package project_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+	"github.com/yur4uwe/cmd-project-manager/project_utils/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (project.ProjectStore, func() project.ProjectStore) {
+		return project.NewMemoryStore(), nil
+	})
+}
+
+func TestJSONFileStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (project.ProjectStore, func() project.ProjectStore) {
+		path := filepath.Join(t.TempDir(), "projects.json")
+		reopen := func() project.ProjectStore { return project.NewJSONFileStore(path) }
+
+		return reopen(), reopen
+	})
+}
+
+func TestJSONFileStoreMergesConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.json")
+	sample := storetest.Sample()
+
+	if err := project.NewJSONFileStore(path).Save(sample); err != nil {
+		t.Fatal(err)
+	}
+
+	ours, theirs := project.NewJSONFileStore(path), project.NewJSONFileStore(path)
+	for _, store := range []*project.JSONFileStore{ours, theirs} {
+		if _, err := store.Load(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Another instance edits alpha and removes gamma, this one edits beta
+	alpha := sample[0]
+	alpha.Description = "edited there"
+	if err := theirs.Put(alpha); err != nil {
+		t.Fatal(err)
+	}
+	if err := theirs.Delete(sample[2].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	beta := sample[1]
+	beta.Description = "edited here"
+	if err := ours.Put(beta); err != nil {
+		t.Fatal(err)
+	}
+
+	if conflicts := ours.TakeConflicts(); len(conflicts) != 0 {
+		t.Fatalf("conflicts %v, want none", conflicts)
+	}
+
+	got, err := project.NewJSONFileStore(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []project.Project{alpha, beta}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,213 @@
+// Package storetest is the conformance suite every project.ProjectStore
+// runs in its tests, so the backends behave the same.
+package storetest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// Open returns a new empty store under test, and a function that opens a
+// second store on the same storage to check what was persisted. The second
+// is nil for stores that only live in memory.
+type Open func(t *testing.T) (project.ProjectStore, func() project.ProjectStore)
+
+// Sample returns projects that use every field of Project.
+func Sample() []project.Project {
+	return []project.Project{
+		{
+			ID:          "01HZAAAAAAAAAAAAAAAAAAAAAA",
+			Name:        "alpha",
+			Description: "the first one",
+			Path:        "/work/alpha",
+			TimeStamp:   "2024-01-02T03:04:05Z",
+			Tags:        []string{"go", "work"},
+			Pinned:      1,
+			Notes:       "Some notes\n",
+			Checklist:   []project.ChecklistItem{{Text: "write tests", Done: true}, {Text: "ship"}},
+			Fields:      map[string]string{"client": "Acme", "due": "2025-01-01"},
+		},
+		{
+			ID:          "01HZBBBBBBBBBBBBBBBBBBBBBB",
+			Name:        "beta",
+			Path:        "/work/beta",
+			TimeStamp:   "2024-02-03T04:05:06Z",
+			Archived:    "2024-03-04T05:06:07Z",
+			ArchiveFile: "/archives/beta.tar.gz",
+		},
+		{
+			ID:        "01HZCCCCCCCCCCCCCCCCCCCCCC",
+			Name:      "gamma",
+			Path:      "/work/gamma",
+			TimeStamp: "2024-03-04T05:06:07Z",
+		},
+	}
+}
+
+var cases = []struct {
+	name string
+	run  func(t *testing.T, store project.ProjectStore)
+}{
+	{"empty", func(t *testing.T, store project.ProjectStore) {
+		expectList(t, store, nil)
+	}},
+	{"put and get", func(t *testing.T, store project.ProjectStore) {
+		for _, p := range Sample() {
+			mustPut(t, store, p)
+		}
+		for _, want := range Sample() {
+			got, err := store.Get(want.ID)
+			if err != nil {
+				t.Fatalf("Get(%s): %v", want.ID, err)
+			}
+			expectEqual(t, got, want)
+		}
+	}},
+	{"put keeps insertion order", func(t *testing.T, store project.ProjectStore) {
+		sample := Sample()
+		for _, i := range []int{2, 0, 1} {
+			mustPut(t, store, sample[i])
+		}
+		expectList(t, store, []project.Project{sample[2], sample[0], sample[1]})
+	}},
+	{"put replaces in place", func(t *testing.T, store project.ProjectStore) {
+		sample := Sample()
+		for _, p := range sample {
+			mustPut(t, store, p)
+		}
+
+		sample[0].Name = "renamed"
+		sample[0].Tags = nil
+		mustPut(t, store, sample[0])
+
+		expectList(t, store, sample)
+	}},
+	{"put assigns missing id", func(t *testing.T, store project.ProjectStore) {
+		mustPut(t, store, project.Project{Name: "new", Path: "/work/new"})
+
+		projects, err := store.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(projects) != 1 || projects[0].ID == "" {
+			t.Fatalf("List() = %+v, want one project with an ID", projects)
+		}
+	}},
+	{"get missing", func(t *testing.T, store project.ProjectStore) {
+		mustPut(t, store, Sample()[0])
+
+		if _, err := store.Get("missing"); !errors.Is(err, project.ErrProjectNotFound) {
+			t.Fatalf("Get(missing) error = %v, want ErrProjectNotFound", err)
+		}
+	}},
+	{"delete", func(t *testing.T, store project.ProjectStore) {
+		sample := Sample()
+		for _, p := range sample {
+			mustPut(t, store, p)
+		}
+
+		if err := store.Delete(sample[1].ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Get(sample[1].ID); !errors.Is(err, project.ErrProjectNotFound) {
+			t.Fatalf("Get after Delete error = %v, want ErrProjectNotFound", err)
+		}
+
+		expectList(t, store, []project.Project{sample[0], sample[2]})
+	}},
+	{"delete missing", func(t *testing.T, store project.ProjectStore) {
+		mustPut(t, store, Sample()[0])
+
+		if err := store.Delete("missing"); !errors.Is(err, project.ErrProjectNotFound) {
+			t.Fatalf("Delete(missing) error = %v, want ErrProjectNotFound", err)
+		}
+		expectList(t, store, Sample()[:1])
+	}},
+	{"save and load", func(t *testing.T, store project.ProjectStore) {
+		if err := store.Save(Sample()); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectEqual(t, loaded, Sample())
+	}},
+	{"save replaces everything", func(t *testing.T, store project.ProjectStore) {
+		for _, p := range Sample() {
+			mustPut(t, store, p)
+		}
+
+		if err := store.Save(Sample()[2:]); err != nil {
+			t.Fatal(err)
+		}
+		expectList(t, store, Sample()[2:])
+	}},
+	{"save assigns missing ids", func(t *testing.T, store project.ProjectStore) {
+		if err := store.Save([]project.Project{{Name: "a"}, {Name: "b"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		projects, err := store.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(projects) != 2 || projects[0].ID == "" || projects[0].ID == projects[1].ID {
+			t.Fatalf("List() = %+v, want two projects with distinct IDs", projects)
+		}
+	}},
+}
+
+// Run runs the conformance suite against the stores open returns.
+func Run(t *testing.T, open Open) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store, reopen := open(t)
+			c.run(t, store)
+
+			if reopen == nil {
+				return
+			}
+
+			// Whatever the store shows now has to come back from storage
+			want, err := store.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectList(t, reopen(), want)
+		})
+	}
+}
+
+func mustPut(t *testing.T, store project.ProjectStore, p project.Project) {
+	t.Helper()
+
+	if err := store.Put(p); err != nil {
+		t.Fatalf("Put(%s): %v", p.Name, err)
+	}
+}
+
+func expectList(t *testing.T, store project.ProjectStore, want []project.Project) {
+	t.Helper()
+
+	got, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	expectEqual(t, got, want)
+}
+
+func expectEqual(t *testing.T, got, want interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package project
+
+import (
+	"sort"
+	"strings"
+)
+
+// TagCount is how many projects carry a tag.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+/*
+ParseTags splits a list of tags like "client, go web" on commas and spaces.
+
+Returns:
+- []string: The tags as NormalizeTags leaves them, empty but not nil for an empty list, so UpdateProject removes the tags.
+*/
+func ParseTags(list string) []string {
+	tags := NormalizeTags(strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}))
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
+
+// NormalizeTags lower cases and trims the tags and drops empty ones and
+// duplicates, keeping the first occurrence. Returns nil if no tag is left,
+// like a project loaded without tags has.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+// HasTags reports whether the project carries every one of tags.
+func HasTags(p Project, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, own := range p.Tags {
+			if strings.EqualFold(own, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterByTags returns the projects carrying every one of tags, all of
+// them if tags is empty.
+func FilterByTags(projects []Project, tags []string) []Project {
+	if len(tags) == 0 {
+		return projects
+	}
+
+	var filtered []Project
+	for _, p := range projects {
+		if HasTags(p, tags) {
+			filtered = append(filtered, p)
+		}
+	}
+
+	return filtered
+}
+
+// CountTags counts the projects of every tag, most used first and by name
+// within the same count.
+func CountTags(projects []Project) []TagCount {
+	counts := make(map[string]int)
+	for _, p := range projects {
+		for _, tag := range NormalizeTags(p.Tags) {
+			counts[tag]++
+		}
+	}
+
+	tag_counts := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tag_counts = append(tag_counts, TagCount{Tag: tag, Count: count})
+	}
+
+	sort.Slice(tag_counts, func(i, j int) bool {
+		if tag_counts[i].Count != tag_counts[j].Count {
+			return tag_counts[i].Count > tag_counts[j].Count
+		}
+		return tag_counts[i].Tag < tag_counts[j].Tag
+	})
+
+	return tag_counts
+}
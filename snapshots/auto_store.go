@@ -0,0 +1,41 @@
+package snapshot
+
+import (
+	"fmt"
+
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+// AutoStore wraps a ProjectStore and takes an automatic snapshot before
+// every project removal.
+type AutoStore struct {
+	project.ProjectStore
+	manager *Manager
+}
+
+func NewAutoStore(store project.ProjectStore, manager *Manager) *AutoStore {
+	return &AutoStore{ProjectStore: store, manager: manager}
+}
+
+func (s *AutoStore) Delete(id string) error {
+	removed, err := s.ProjectStore.Get(id)
+	if err != nil {
+		return err
+	}
+
+	reason := fmt.Sprintf("remove project %q", removed.Name)
+	if _, err := s.manager.Capture("before-remove", reason, true, s.ProjectStore); err != nil {
+		return fmt.Errorf("snapshot before removing: %w", err)
+	}
+
+	return s.ProjectStore.Delete(id)
+}
+
+// TakeConflicts passes through the conflicts of the wrapped store.
+func (s *AutoStore) TakeConflicts() []project.MergeConflict {
+	if reporter, ok := s.ProjectStore.(project.ConflictReporter); ok {
+		return reporter.TakeConflicts()
+	}
+
+	return nil
+}
@@ -0,0 +1,313 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	file_utils "github.com/yur4uwe/cmd-project-manager/file_utils"
+	path_manager "github.com/yur4uwe/cmd-project-manager/manage_paths"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+)
+
+const (
+	PROJECTS_FILE = "projects.json"
+	HISTORY_FILE  = "directory_history.json"
+	META_FILE     = "snapshot.json"
+	LAUNCHES_FILE = "launches"
+)
+
+// Snapshot is a saved copy of the project registry and the directory
+// history, each in its own directory under the snapshot directory.
+type Snapshot struct {
+	ID        string `json:"-"`
+	Label     string `json:"label"`
+	Reason    string `json:"reason"`
+	TimeStamp string `json:"time_stamp"`
+	// Automatic snapshots are pruned, the ones made by the user are kept.
+	Automatic bool `json:"automatic"`
+}
+
+func (s Snapshot) String() string {
+	if s.Reason == "" || s.Reason == s.Label {
+		return fmt.Sprintf("%s  %s", s.TimeStamp, s.Label)
+	}
+
+	return fmt.Sprintf("%s  %s (%s)", s.TimeStamp, s.Label, s.Reason)
+}
+
+// Manager creates, lists and reads the snapshots in one directory.
+type Manager struct {
+	dir string
+	// Keep is how many automatic snapshots survive pruning, 0 keeps all.
+	Keep int
+}
+
+func NewManager(dir string, keep int) *Manager {
+	return &Manager{dir: dir, Keep: keep}
+}
+
+/*
+Create stores a new snapshot from the raw contents of the two registry files.
+
+Parameters:
+- label: A short name for the snapshot.
+- reason: Why it was taken, shown next to the label.
+- automatic: Whether the snapshot was taken without the user asking for it.
+- projects_json: The projects file, in any version DecodeRegistry understands.
+- history_json: The directory history file.
+
+Returns:
+- Snapshot: The stored snapshot.
+- error: An error if it could not be written.
+*/
+func (m *Manager) Create(label, reason string, automatic bool, projects_json, history_json []byte) (Snapshot, error) {
+	now := time.Now()
+
+	snapshot := Snapshot{
+		ID:        now.Format("20060102-150405.000") + "-" + sanitizeLabel(label),
+		Label:     label,
+		Reason:    reason,
+		TimeStamp: now.Format(time.RFC3339),
+		Automatic: automatic,
+	}
+
+	dir := filepath.Join(m.dir, snapshot.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Snapshot{}, fmt.Errorf("create snapshot directory: %w", err)
+	}
+
+	meta, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	files := map[string][]byte{
+		PROJECTS_FILE: projects_json,
+		HISTORY_FILE:  history_json,
+		META_FILE:     meta,
+	}
+
+	for name, data := range files {
+		if data == nil {
+			continue
+		}
+		if err := file_utils.WriteFileAtomic(filepath.Join(dir, name), data, 0644); err != nil {
+			return Snapshot{}, fmt.Errorf("write snapshot: %w", err)
+		}
+	}
+
+	log.Println("Created snapshot ", snapshot.ID)
+
+	if automatic {
+		m.prune()
+	}
+
+	return snapshot, nil
+}
+
+// Capture snapshots the registry as the store sees it now, together with
+// the current directory history.
+func (m *Manager) Capture(label, reason string, automatic bool, store project.ProjectStore) (Snapshot, error) {
+	projects, err := store.List()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("read projects for snapshot: %w", err)
+	}
+
+	projects_json, err := project.EncodeRegistry(projects)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("encode projects for snapshot: %w", err)
+	}
+
+	history, err := path_manager.ReadRecentPathsFromFile()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("read path history for snapshot: %w", err)
+	}
+
+	history_json, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("encode path history for snapshot: %w", err)
+	}
+
+	return m.Create(label, reason, automatic, projects_json, history_json)
+}
+
+// List returns every snapshot, newest first.
+func (m *Manager) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read snapshot directory: %w", err)
+	}
+
+	var snapshots []Snapshot
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		meta, err := os.ReadFile(filepath.Join(m.dir, entry.Name(), META_FILE))
+		if err != nil {
+			log.Println("Skipping snapshot without metadata: ", entry.Name())
+			continue
+		}
+
+		var snapshot Snapshot
+		if err := json.Unmarshal(meta, &snapshot); err != nil {
+			log.Println("Skipping snapshot with broken metadata: ", entry.Name(), err)
+			continue
+		}
+		snapshot.ID = entry.Name()
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].ID > snapshots[j].ID
+	})
+
+	return snapshots, nil
+}
+
+// Read returns the projects and the directory history stored in snapshot.
+func (m *Manager) Read(snapshot Snapshot) ([]project.Project, []path_manager.RecentPath, error) {
+	dir := filepath.Join(m.dir, snapshot.ID)
+
+	projects_json, err := os.ReadFile(filepath.Join(dir, PROJECTS_FILE))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("read snapshot projects: %w", err)
+	}
+
+	var projects []project.Project
+	if len(projects_json) > 0 {
+		projects, _, err = project.DecodeRegistry(projects_json)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse snapshot projects: %w", err)
+		}
+	}
+
+	history_json, err := os.ReadFile(filepath.Join(dir, HISTORY_FILE))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("read snapshot path history: %w", err)
+	}
+
+	var history []path_manager.RecentPath
+	if len(history_json) > 0 {
+		if err := json.Unmarshal(history_json, &history); err != nil {
+			return nil, nil, fmt.Errorf("parse snapshot path history: %w", err)
+		}
+	}
+
+	return projects, history, nil
+}
+
+/*
+Restore replaces the registry and the directory history with the contents
+of snapshot. The current state is snapshotted first, so a restore can
+itself be undone by restoring that snapshot.
+
+Parameters:
+- snapshot: The snapshot to restore.
+- store: The project store to write the projects to.
+
+Returns:
+- error: An error if reading the snapshot or writing the stores failed.
+*/
+func (m *Manager) Restore(snapshot Snapshot, store project.ProjectStore) error {
+	projects, history, err := m.Read(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.Capture("before-restore", "restore of "+snapshot.Label, true, store); err != nil {
+		return err
+	}
+
+	if err := store.Save(projects); err != nil {
+		return fmt.Errorf("restore projects: %w", err)
+	}
+
+	if err := path_manager.SaveRecentPaths(history); err != nil {
+		return fmt.Errorf("restore path history: %w", err)
+	}
+
+	return nil
+}
+
+// CountLaunch records a program start and reports whether a scheduled
+// snapshot is due, which is every `every` launches. 0 disables the schedule.
+func (m *Manager) CountLaunch(every int) (bool, error) {
+	if every <= 0 {
+		return false, nil
+	}
+
+	path := filepath.Join(m.dir, LAUNCHES_FILE)
+
+	launches := 0
+	if data, err := os.ReadFile(path); err == nil {
+		launches, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	launches++
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return false, fmt.Errorf("create snapshot directory: %w", err)
+	}
+
+	if err := file_utils.WriteFileAtomic(path, []byte(strconv.Itoa(launches)), 0644); err != nil {
+		return false, fmt.Errorf("count launch: %w", err)
+	}
+
+	return launches%every == 0, nil
+}
+
+// prune removes the oldest automatic snapshots beyond Keep.
+func (m *Manager) prune() {
+	if m.Keep <= 0 {
+		return
+	}
+
+	snapshots, err := m.List()
+	if err != nil {
+		log.Println("Failed to list snapshots for pruning: ", err)
+		return
+	}
+
+	kept := 0
+	for _, snapshot := range snapshots {
+		if !snapshot.Automatic {
+			continue
+		}
+
+		kept++
+		if kept <= m.Keep {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(m.dir, snapshot.ID)); err != nil {
+			log.Println("Failed to prune snapshot ", snapshot.ID, err)
+		}
+	}
+}
+
+func sanitizeLabel(label string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, label)
+
+	if sanitized == "" {
+		return "snapshot"
+	}
+
+	return sanitized
+}
@@ -0,0 +1,455 @@
+package sqlite_store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	path_manager "github.com/yur4uwe/cmd-project-manager/manage_paths"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS projects (
+	id          TEXT PRIMARY KEY,
+	position    INTEGER NOT NULL,
+	name        TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	path        TEXT NOT NULL DEFAULT '',
+	time_stamp  TEXT NOT NULL DEFAULT '',
+	data        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS projects_name ON projects (name COLLATE NOCASE);
+CREATE INDEX IF NOT EXISTS projects_path ON projects (path);
+CREATE INDEX IF NOT EXISTS projects_time_stamp ON projects (time_stamp);
+CREATE INDEX IF NOT EXISTS projects_modified ON projects (julianday(time_stamp));
+
+CREATE TABLE IF NOT EXISTS project_tags (
+	project_id TEXT NOT NULL,
+	tag        TEXT NOT NULL,
+	PRIMARY KEY (project_id, tag)
+);
+CREATE INDEX IF NOT EXISTS project_tags_tag ON project_tags (tag COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS recent_paths (
+	path         TEXT PRIMARY KEY,
+	position     INTEGER NOT NULL,
+	last_access  TEXT NOT NULL DEFAULT '',
+	times_opened INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS recent_paths_last_access ON recent_paths (last_access);
+CREATE INDEX IF NOT EXISTS recent_paths_times_opened ON recent_paths (times_opened);
+
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+// DB is an SQLite database holding both the project registry and the
+// directory history. Projects and History give the two views on it.
+type DB struct {
+	db *sql.DB
+}
+
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database %s: %w", path, err)
+	}
+
+	// A single connection serializes writers inside this process, and the
+	// busy timeout makes other instances wait for the lock instead of failing.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("PRAGMA busy_timeout = 5000"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("configure sqlite database: %w", err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create sqlite schema: %w", err)
+	}
+
+	d := &DB{db: db}
+	if err := d.indexTags(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// indexTags fills project_tags from the stored projects, once, for
+// databases created before the table existed.
+func (d *DB) indexTags() error {
+	var indexed string
+	err := d.db.QueryRow("SELECT value FROM meta WHERE key = 'tags_indexed'").Scan(&indexed)
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("check tag index: %w", err)
+	}
+
+	projects, err := d.Projects().List()
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tag index: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, p := range projects {
+		if err := writeTags(tx, p); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO meta (key, value) VALUES ('tags_indexed', ?)", time.Now().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("record tag index: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tag index: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+func (d *DB) Projects() *ProjectStore {
+	return &ProjectStore{db: d.db}
+}
+
+func (d *DB) History() *HistoryStore {
+	return &HistoryStore{db: d.db}
+}
+
+/*
+ImportJSON copies the registry and history of the JSON backend into the
+database, once. Later calls do nothing, so it is safe to call on every start.
+
+Parameters:
+- projects: The JSON project store to import from.
+- history: The JSON history store to import from.
+
+Returns:
+- bool: Whether the import ran.
+- error: An error if reading the JSON files or writing the database failed.
+*/
+func (d *DB) ImportJSON(projects project.ProjectStore, history path_manager.HistoryStore) (bool, error) {
+	var imported string
+	err := d.db.QueryRow("SELECT value FROM meta WHERE key = 'json_imported'").Scan(&imported)
+	if err == nil {
+		return false, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("check json import: %w", err)
+	}
+
+	log.Println("Import JSON registry into SQLite")
+
+	project_list, err := projects.Load()
+	if err != nil {
+		return false, fmt.Errorf("read projects to import: %w", err)
+	}
+
+	recent_paths, err := history.Load()
+	if err != nil {
+		return false, fmt.Errorf("read path history to import: %w", err)
+	}
+
+	if err := d.Projects().Save(project_list); err != nil {
+		return false, err
+	}
+
+	if err := d.History().Save(recent_paths); err != nil {
+		return false, err
+	}
+
+	_, err = d.db.Exec("INSERT INTO meta (key, value) VALUES ('json_imported', ?)", time.Now().Format(time.RFC3339))
+	if err != nil {
+		return false, fmt.Errorf("record json import: %w", err)
+	}
+
+	return true, nil
+}
+
+// ProjectStore implements project.ProjectStore on top of the projects table.
+// The whole record is kept as JSON next to the indexed columns, so fields
+// without a column of their own round-trip unchanged.
+type ProjectStore struct {
+	db *sql.DB
+}
+
+func (s *ProjectStore) Load() ([]project.Project, error) {
+	return s.List()
+}
+
+func (s *ProjectStore) Save(projects []project.Project) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin save projects: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM projects"); err != nil {
+		return fmt.Errorf("clear projects: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM project_tags"); err != nil {
+		return fmt.Errorf("clear project tags: %w", err)
+	}
+
+	projects = append([]project.Project(nil), projects...)
+	project.AssignMissingIDs(projects)
+
+	for i, p := range projects {
+		if err := insertProject(tx, p, i); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit save projects: %w", err)
+	}
+
+	return nil
+}
+
+func insertProject(tx *sql.Tx, p project.Project, position int) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encode project %q: %w", p.ID, err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO projects (id, position, name, description, path, time_stamp, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			description = excluded.description,
+			path = excluded.path,
+			time_stamp = excluded.time_stamp,
+			data = excluded.data`,
+		p.ID, position, p.Name, p.Description, p.Path, p.TimeStamp, string(data))
+	if err != nil {
+		return fmt.Errorf("write project %q: %w", p.ID, err)
+	}
+
+	return writeTags(tx, p)
+}
+
+// writeTags replaces the rows of p in project_tags with its tags.
+func writeTags(tx *sql.Tx, p project.Project) error {
+	if _, err := tx.Exec("DELETE FROM project_tags WHERE project_id = ?", p.ID); err != nil {
+		return fmt.Errorf("clear tags of project %q: %w", p.ID, err)
+	}
+
+	for _, tag := range p.Tags {
+		_, err := tx.Exec("INSERT INTO project_tags (project_id, tag) VALUES (?, ?) ON CONFLICT DO NOTHING", p.ID, tag)
+		if err != nil {
+			return fmt.Errorf("write tags of project %q: %w", p.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *ProjectStore) Get(id string) (project.Project, error) {
+	projects, err := s.query("SELECT data FROM projects WHERE id = ?", id)
+	if err != nil {
+		return project.Project{}, err
+	}
+
+	if len(projects) == 0 {
+		return project.Project{}, fmt.Errorf("get project %q: %w", id, project.ErrProjectNotFound)
+	}
+
+	return projects[0], nil
+}
+
+// Put replaces the project with the same ID or appends it as a new one.
+func (s *ProjectStore) Put(p project.Project) error {
+	if p.ID == "" {
+		p.ID = project.NewProjectID()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin put project: %w", err)
+	}
+	defer tx.Rollback()
+
+	var position int
+	err = tx.QueryRow("SELECT COALESCE(MAX(position) + 1, 0) FROM projects").Scan(&position)
+	if err != nil {
+		return fmt.Errorf("find project position: %w", err)
+	}
+
+	if err := insertProject(tx, p, position); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit put project: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ProjectStore) Delete(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin delete project: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM projects WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("delete project %q: %w", id, err)
+	}
+
+	if removed, _ := result.RowsAffected(); removed == 0 {
+		return fmt.Errorf("delete project %q: %w", id, project.ErrProjectNotFound)
+	}
+
+	if _, err := tx.Exec("DELETE FROM project_tags WHERE project_id = ?", id); err != nil {
+		return fmt.Errorf("delete tags of project %q: %w", id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit delete project: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ProjectStore) List() ([]project.Project, error) {
+	return s.query("SELECT data FROM projects ORDER BY position")
+}
+
+// QueryProjects implements project.ProjectQuerier with the tag, timestamp
+// and path history indexes, so only the matching rows are decoded.
+func (s *ProjectStore) QueryProjects(query project.ProjectQuery) ([]project.Project, error) {
+	sql_query := "SELECT p.data FROM projects p"
+	var args []interface{}
+
+	if query.ByOpened {
+		sql_query += " LEFT JOIN recent_paths r ON r.path = p.path"
+	}
+
+	sql_query += " WHERE 1 = 1"
+	for _, tag := range query.Tags {
+		sql_query += " AND p.id IN (SELECT project_id FROM project_tags WHERE tag = ? COLLATE NOCASE)"
+		args = append(args, tag)
+	}
+
+	if !query.Since.IsZero() {
+		// julianday compares timestamps written in different time zones
+		sql_query += " AND julianday(p.time_stamp) >= julianday(?)"
+		args = append(args, query.Since.UTC().Format(time.RFC3339))
+	}
+
+	if query.ByOpened {
+		sql_query += " ORDER BY COALESCE(r.times_opened, 0) DESC, COALESCE(r.last_access, '') DESC, p.position"
+	} else {
+		sql_query += " ORDER BY p.position"
+	}
+
+	return s.query(sql_query, args...)
+}
+
+func (s *ProjectStore) query(query string, args ...interface{}) ([]project.Project, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []project.Project
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("read project row: %w", err)
+		}
+
+		var p project.Project
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			return nil, fmt.Errorf("decode project row: %w", err)
+		}
+		projects = append(projects, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query projects: %w", err)
+	}
+
+	return projects, nil
+}
+
+// HistoryStore implements path_manager.HistoryStore on top of the
+// recent_paths table.
+type HistoryStore struct {
+	db *sql.DB
+}
+
+func (s *HistoryStore) Load() ([]path_manager.RecentPath, error) {
+	rows, err := s.db.Query("SELECT path, last_access, times_opened FROM recent_paths ORDER BY position")
+	if err != nil {
+		return nil, fmt.Errorf("query path history: %w", err)
+	}
+	defer rows.Close()
+
+	var recent_paths []path_manager.RecentPath
+	for rows.Next() {
+		var recent_path path_manager.RecentPath
+		if err := rows.Scan(&recent_path.Path, &recent_path.LastAccess, &recent_path.TimesOpened); err != nil {
+			return nil, fmt.Errorf("read path history row: %w", err)
+		}
+		recent_paths = append(recent_paths, recent_path)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query path history: %w", err)
+	}
+
+	return recent_paths, nil
+}
+
+func (s *HistoryStore) Save(recent_paths []path_manager.RecentPath) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin save path history: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM recent_paths"); err != nil {
+		return fmt.Errorf("clear path history: %w", err)
+	}
+
+	for i, recent_path := range recent_paths {
+		_, err := tx.Exec(`INSERT INTO recent_paths (path, position, last_access, times_opened)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (path) DO NOTHING`,
+			recent_path.Path, i, recent_path.LastAccess, recent_path.TimesOpened)
+		if err != nil {
+			return fmt.Errorf("write path history %q: %w", recent_path.Path, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit save path history: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package sqlite_store
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	path_manager "github.com/yur4uwe/cmd-project-manager/manage_paths"
+	project "github.com/yur4uwe/cmd-project-manager/project_utils"
+	"github.com/yur4uwe/cmd-project-manager/project_utils/storetest"
+)
+
+// openDB opens a database in a temporary directory, closed with the test.
+func openDB(t *testing.T, path string) *DB {
+	t.Helper()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestProjectStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (project.ProjectStore, func() project.ProjectStore) {
+		path := filepath.Join(t.TempDir(), "projects.db")
+		reopen := func() project.ProjectStore { return openDB(t, path).Projects() }
+
+		return reopen(), reopen
+	})
+}
+
+func TestQueryProjects(t *testing.T) {
+	db := openDB(t, filepath.Join(t.TempDir(), "projects.db"))
+	store := db.Projects()
+
+	sample := storetest.Sample()
+	sample[1].Tags = []string{"work"}
+	// Written in another time zone, one hour before alpha
+	sample[2].TimeStamp = "2024-01-02T04:04:05+02:00"
+	if err := store.Save(sample); err != nil {
+		t.Fatal(err)
+	}
+
+	path_manager.SetStore(db.History())
+	defer path_manager.SetStore(path_manager.NewJSONHistoryStore(".directory_history.json"))
+	err := db.History().Save([]path_manager.RecentPath{
+		{Path: sample[2].Path, LastAccess: "2024-05-01T00:00:00Z", TimesOpened: 5},
+		{Path: sample[1].Path, LastAccess: "2024-04-01T00:00:00Z", TimesOpened: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	since, _ := time.Parse(time.RFC3339, "2024-01-02T03:00:00Z")
+
+	queries := []struct {
+		name  string
+		query project.ProjectQuery
+		want  []string
+	}{
+		{"all", project.ProjectQuery{}, []string{"alpha", "beta", "gamma"}},
+		{"tag", project.ProjectQuery{Tags: []string{"work"}}, []string{"alpha", "beta"}},
+		{"tags", project.ProjectQuery{Tags: []string{"WORK", "go"}}, []string{"alpha"}},
+		{"since", project.ProjectQuery{Since: since}, []string{"alpha", "beta"}},
+		{"opened", project.ProjectQuery{ByOpened: true}, []string{"gamma", "beta", "alpha"}},
+		{"tag by opened", project.ProjectQuery{Tags: []string{"work"}, ByOpened: true}, []string{"beta", "alpha"}},
+	}
+
+	for _, q := range queries {
+		t.Run(q.name, func(t *testing.T) {
+			indexed, err := store.QueryProjects(q.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			all, err := store.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Both ways of answering have to agree
+			for _, got := range [][]project.Project{indexed, project.QueryProjects(all, q.query)} {
+				var names []string
+				for _, p := range got {
+					names = append(names, p.Name)
+				}
+				if !reflect.DeepEqual(names, q.want) {
+					t.Errorf("got %v, want %v", names, q.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTagIndexBackfill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.db")
+	db := openDB(t, path)
+	if err := db.Projects().Save(storetest.Sample()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A database from before the tag table
+	if _, err := db.db.Exec("DELETE FROM project_tags; DELETE FROM meta WHERE key = 'tags_indexed'"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	got, err := openDB(t, path).Projects().QueryProjects(project.ProjectQuery{Tags: []string{"go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "alpha" {
+		t.Fatalf("got %+v, want alpha", got)
+	}
+}